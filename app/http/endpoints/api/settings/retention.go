@@ -0,0 +1,105 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+
+	dbclient "github.com/TicketsBot-cloud/dashboard/database"
+	"github.com/TicketsBot-cloud/dashboard/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	minRetentionDays = 1
+	maxRetentionDays = 3650
+
+	retentionLogPageLimit = 50
+)
+
+func GetRetentionHandler(ctx *gin.Context) {
+	guildId := ctx.Keys["guildid"].(uint64)
+
+	retention, err := dbclient.Dashboard.TranscriptRetention.Get(ctx, guildId)
+	if err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	ctx.JSON(200, retention)
+}
+
+// UpdateRetentionHandler replaces the guild's retention period and panel overrides. Transcripts older than the new
+// period are purged the next time the retention job runs.
+func UpdateRetentionHandler(ctx *gin.Context) {
+	guildId := ctx.Keys["guildid"].(uint64)
+
+	var retention dbclient.TranscriptRetention
+	if err := ctx.BindJSON(&retention); err != nil {
+		ctx.JSON(400, utils.ErrorJson(err))
+		return
+	}
+
+	if retention.Days != nil && !isValidRetention(*retention.Days) {
+		ctx.JSON(400, utils.ErrorStr(fmt.Sprintf("Retention period must be between %d and %d days", minRetentionDays, maxRetentionDays)))
+		return
+	}
+
+	if len(retention.Panels) > 0 {
+		panels, err := dbclient.Client.Panel.GetByGuild(ctx, guildId)
+		if err != nil {
+			ctx.JSON(500, utils.ErrorJson(err))
+			return
+		}
+
+		guildPanels := make(map[int]bool)
+		for _, panel := range panels {
+			guildPanels[panel.PanelId] = true
+		}
+
+		for panelId, days := range retention.Panels {
+			if !guildPanels[panelId] {
+				ctx.JSON(400, utils.ErrorStr("Invalid panel"))
+				return
+			}
+
+			if !isValidRetention(days) {
+				ctx.JSON(400, utils.ErrorStr(fmt.Sprintf("Retention period must be between %d and %d days", minRetentionDays, maxRetentionDays)))
+				return
+			}
+		}
+	}
+
+	if err := dbclient.Dashboard.TranscriptRetention.Set(ctx, guildId, retention); err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	ctx.Status(204)
+}
+
+// GetRetentionLogHandler lists the transcripts that have been purged, newest first. Pass ?before=<id> to page.
+func GetRetentionLogHandler(ctx *gin.Context) {
+	guildId := ctx.Keys["guildid"].(uint64)
+
+	var before int
+	if raw := ctx.Query("before"); raw != "" {
+		var err error
+		before, err = strconv.Atoi(raw)
+		if err != nil || before < 0 {
+			ctx.JSON(400, utils.ErrorStr("Invalid before ID"))
+			return
+		}
+	}
+
+	purges, err := dbclient.Dashboard.TranscriptPurgeLog.GetByGuild(ctx, guildId, before, retentionLogPageLimit)
+	if err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	ctx.JSON(200, purges)
+}
+
+func isValidRetention(days int) bool {
+	return days >= minRetentionDays && days <= maxRetentionDays
+}
@@ -29,6 +29,7 @@ type (
 		UsersCanClose     bool                  `json:"users_can_close"`
 		CloseConfirmation bool                  `json:"close_confirmation"`
 		FeedbackEnabled   bool                  `json:"feedback_enabled"`
+		RatingWindow      int                   `json:"rating_window"` // Hours
 		Language          *string               `json:"language"`
 	}
 
@@ -140,6 +141,17 @@ func GetSettingsHandler(ctx *gin.Context) {
 		return
 	})
 
+	// rating window
+	group.Go(func() error {
+		window, err := dbclient.Dashboard.RatingWindow.Get(ctx, guildId)
+		if err != nil {
+			return err
+		}
+
+		settings.RatingWindow = int(window.Hours())
+		return nil
+	})
+
 	// language
 	group.Go(func() error {
 		locale, err := dbclient.Client.ActiveLanguage.Get(ctx, guildId)
@@ -87,6 +87,7 @@ func UpdateSettingsHandler(ctx *gin.Context) {
 	settings.updateUsersCanClose(guildId)
 	settings.updateCloseConfirmation(guildId)
 	settings.updateFeedbackEnabled(guildId)
+	validRatingWindow := settings.updateRatingWindow(guildId)
 
 	ctx.JSON(200, gin.H{
 		"welcome_message": validWelcomeMessage,
@@ -94,6 +95,7 @@ func UpdateSettingsHandler(ctx *gin.Context) {
 		"archive_channel": validArchiveChannel,
 		"category":        validCategory,
 		"naming_scheme":   validNamingScheme,
+		"rating_window":   validRatingWindow,
 		"error":           errStr,
 	})
 }
@@ -281,6 +283,21 @@ func (s *Settings) updateTicketLimit(guildId uint64) bool {
 	return true
 }
 
+// Ratings may be left for up to 30 days after a ticket is closed. Clients that do not know about the setting omit it,
+// so 0 leaves the window unchanged.
+func (s *Settings) updateRatingWindow(guildId uint64) bool {
+	if s.RatingWindow == 0 {
+		return true
+	}
+
+	if s.RatingWindow < 1 || s.RatingWindow > 24*30 {
+		return false
+	}
+
+	go dbclient.Dashboard.RatingWindow.Set(context.Background(), guildId, time.Duration(s.RatingWindow)*time.Hour)
+	return true
+}
+
 func (s *Settings) updateCategory(channels []channel.Channel, guildId uint64) bool {
 	var valid bool
 	for _, ch := range channels {
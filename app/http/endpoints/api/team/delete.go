@@ -4,8 +4,11 @@ import (
 	"strconv"
 
 	dbclient "github.com/TicketsBot-cloud/dashboard/database"
+	"github.com/TicketsBot-cloud/dashboard/log"
+	"github.com/TicketsBot-cloud/dashboard/redis"
 	"github.com/TicketsBot-cloud/dashboard/utils"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 func DeleteTeam(ctx *gin.Context) {
@@ -34,5 +37,10 @@ func DeleteTeam(ctx *gin.Context) {
 		return
 	}
 
+	// Every member of the team may have lost access to tickets
+	if err := redis.Client.PublishPermissionRevalidation(redis.DefaultContext(), redis.PermissionRevalidation{GuildId: guildId}); err != nil {
+		log.Logger.Warn("Failed to publish live-chat permission revalidation", zap.Error(err), zap.Uint64("guild_id", guildId))
+	}
+
 	ctx.JSON(200, utils.SuccessResponse)
 }
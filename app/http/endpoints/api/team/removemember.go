@@ -8,11 +8,14 @@ import (
 
 	"github.com/TicketsBot-cloud/dashboard/botcontext"
 	dbclient "github.com/TicketsBot-cloud/dashboard/database"
+	"github.com/TicketsBot-cloud/dashboard/log"
+	"github.com/TicketsBot-cloud/dashboard/redis"
 	"github.com/TicketsBot-cloud/dashboard/utils"
 	"github.com/TicketsBot-cloud/database"
 	"github.com/gin-gonic/gin"
 	"github.com/rxdn/gdl/rest"
 	"github.com/rxdn/gdl/rest/request"
+	"go.uber.org/zap"
 )
 
 func RemoveMember(ctx *gin.Context) {
@@ -100,6 +103,8 @@ func removeDefaultMember(ctx *gin.Context, guildId, selfId, snowflake uint64, en
 		return
 	}
 
+	revokeLiveChatAccess(guildId, snowflake, entityType)
+
 	// Remove on-call role
 	metadata, err := dbclient.Client.GuildMetadata.Get(ctx, guildId)
 	if err != nil {
@@ -181,6 +186,8 @@ func removeTeamMember(ctx *gin.Context, teamId int, guildId, snowflake uint64, e
 		return
 	}
 
+	revokeLiveChatAccess(guildId, snowflake, entityType)
+
 	// Remove on-call role
 	if team.OnCallRole != nil {
 		botContext, err := botcontext.ContextForGuild(guildId)
@@ -275,3 +282,19 @@ func isUnknownRoleError(err error) bool {
 
 	return false
 }
+
+// revokeLiveChatAccess makes open live-chat sockets re-check their permissions straight away. Removing a role may
+// affect any member, so every socket in the guild is re-checked.
+func revokeLiveChatAccess(guildId, snowflake uint64, entityType entityType) {
+	data := redis.PermissionRevalidation{
+		GuildId: guildId,
+	}
+
+	if entityType == entityTypeUser {
+		data.UserId = &snowflake
+	}
+
+	if err := redis.Client.PublishPermissionRevalidation(redis.DefaultContext(), data); err != nil {
+		log.Logger.Warn("Failed to publish live-chat permission revalidation", zap.Error(err), zap.Uint64("guild_id", guildId))
+	}
+}
@@ -0,0 +1,226 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/TicketsBot-cloud/common/permission"
+	"github.com/TicketsBot-cloud/dashboard/app"
+	"github.com/TicketsBot-cloud/dashboard/botcontext"
+	dbclient "github.com/TicketsBot-cloud/dashboard/database"
+	"github.com/TicketsBot-cloud/dashboard/log"
+	"github.com/TicketsBot-cloud/dashboard/redis"
+	"github.com/TicketsBot-cloud/dashboard/utils"
+	"github.com/TicketsBot-cloud/database"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type transferBody struct {
+	UserId uint64 `json:"user_id,string"`
+}
+
+func ClaimTicket(ctx *gin.Context) {
+	guildId := ctx.Keys["guildid"].(uint64)
+	userId := ctx.Keys["userid"].(uint64)
+
+	ticket, claimedBy, ok := getClaimableTicket(ctx, guildId, userId)
+	if !ok {
+		return
+	}
+
+	if claimedBy != 0 {
+		ctx.JSON(http.StatusConflict, utils.ErrorStr("This ticket has already been claimed"))
+		return
+	}
+
+	claimTicket(ctx, ticket, userId, userId, "Ticket Claimed", fmt.Sprintf("This ticket has been claimed by <@%d>", userId))
+}
+
+func UnclaimTicket(ctx *gin.Context) {
+	guildId := ctx.Keys["guildid"].(uint64)
+	userId := ctx.Keys["userid"].(uint64)
+
+	ticket, claimedBy, ok := getClaimableTicket(ctx, guildId, userId)
+	if !ok {
+		return
+	}
+
+	if claimedBy == 0 {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorStr("This ticket is not claimed"))
+		return
+	}
+
+	if !canReassign(ctx, guildId, userId, claimedBy) {
+		return
+	}
+
+	botContext, err := botcontext.ContextForGuild(guildId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorJson(err))
+		return
+	}
+
+	if err := utils.UnclaimTicket(ctx, botContext, ticket); err != nil {
+		_ = ctx.AbortWithError(http.StatusInternalServerError, app.NewServerError(err))
+		return
+	}
+
+	publishTicketEvent(ctx, redis.TicketEventUnclaimed, ticket, nil, userId)
+	notifyTicket(ctx, botContext, ticket, "Ticket Unclaimed", "This ticket is no longer claimed")
+	ctx.Status(http.StatusNoContent)
+}
+
+// TransferTicket reassigns the ticket to another staff member. Unclaimed tickets can be assigned by any staff member who
+// can view the ticket, but only the claimer or an admin can take a claimed ticket away from its claimer.
+func TransferTicket(ctx *gin.Context) {
+	guildId := ctx.Keys["guildid"].(uint64)
+	userId := ctx.Keys["userid"].(uint64)
+
+	var body transferBody
+	if err := ctx.BindJSON(&body); err != nil || body.UserId == 0 {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorStr("Invalid request body"))
+		return
+	}
+
+	ticket, claimedBy, ok := getClaimableTicket(ctx, guildId, userId)
+	if !ok {
+		return
+	}
+
+	if claimedBy == body.UserId {
+		ctx.JSON(http.StatusConflict, utils.ErrorStr("This ticket is already claimed by that user"))
+		return
+	}
+
+	if claimedBy != 0 && !canReassign(ctx, guildId, userId, claimedBy) {
+		return
+	}
+
+	// The new claimer must be a staff member who would be able to see the ticket
+	permLevel, err := utils.GetPermissionLevel(ctx, guildId, body.UserId)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorStr("User not found in server"))
+		return
+	}
+
+	if permLevel < permission.Support {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorStr("Tickets can only be transferred to staff members"))
+		return
+	}
+
+	hasPermission, requestErr := utils.HasPermissionToViewTicket(ctx, guildId, body.UserId, ticket)
+	if requestErr != nil {
+		ctx.JSON(requestErr.StatusCode, utils.ErrorJson(requestErr))
+		return
+	}
+
+	if !hasPermission {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorStr("That user does not have permission to view this ticket"))
+		return
+	}
+
+	claimTicket(ctx, ticket, body.UserId, userId, "Ticket Transferred", fmt.Sprintf("This ticket has been transferred to <@%d> by <@%d>", body.UserId, userId))
+}
+
+// getClaimableTicket fetches the ticket and its current claimer, verifying that the ticket is open, can be claimed,
+// and that the user has permission to view it. If ok is false, a response has already been written.
+func getClaimableTicket(ctx *gin.Context, guildId, userId uint64) (ticket database.Ticket, claimedBy uint64, ok bool) {
+	ticketId, err := strconv.Atoi(ctx.Param("ticketId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorStr("Invalid ticket ID"))
+		return
+	}
+
+	ticket, err = dbclient.Client.Tickets.Get(ctx, ticketId, guildId)
+	if err != nil {
+		_ = ctx.AbortWithError(http.StatusInternalServerError, app.NewServerError(err))
+		return
+	}
+
+	if ticket.UserId == 0 || !ticket.Open {
+		ctx.JSON(http.StatusNotFound, utils.ErrorStr("Ticket not found"))
+		return
+	}
+
+	if ticket.IsThread {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorStr("Tickets created as threads cannot be claimed"))
+		return
+	}
+
+	if ticket.ChannelId == nil {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorStr("Ticket channel not found"))
+		return
+	}
+
+	hasPermission, requestErr := utils.HasPermissionToViewTicket(ctx, guildId, userId, ticket)
+	if requestErr != nil {
+		ctx.JSON(requestErr.StatusCode, utils.ErrorJson(requestErr))
+		return
+	}
+
+	if !hasPermission {
+		ctx.JSON(http.StatusForbidden, utils.ErrorStr("You do not have permission to manage this ticket"))
+		return
+	}
+
+	claimedBy, err = dbclient.Client.TicketClaims.Get(ctx, guildId, ticket.Id)
+	if err != nil {
+		_ = ctx.AbortWithError(http.StatusInternalServerError, app.NewServerError(err))
+		return
+	}
+
+	return ticket, claimedBy, true
+}
+
+// canReassign returns whether the user may unclaim or transfer a ticket claimed by claimedBy: only the claimer and
+// admins may do so, the same as the /unclaim command. If false, a response has already been written.
+func canReassign(ctx *gin.Context, guildId, userId, claimedBy uint64) bool {
+	if userId == claimedBy {
+		return true
+	}
+
+	permLevel, err := utils.GetPermissionLevel(ctx, guildId, userId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorJson(err))
+		return false
+	}
+
+	if permLevel < permission.Admin {
+		ctx.JSON(http.StatusForbidden, utils.ErrorStr("Only the claimer or an admin can reassign this ticket"))
+		return false
+	}
+
+	return true
+}
+
+func claimTicket(ctx *gin.Context, ticket database.Ticket, claimerId, actorId uint64, title, description string) {
+	botContext, err := botcontext.ContextForGuild(ticket.GuildId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorJson(err))
+		return
+	}
+
+	if err := utils.ClaimTicket(ctx, botContext, ticket, claimerId); err != nil {
+		_ = ctx.AbortWithError(http.StatusInternalServerError, app.NewServerError(err))
+		return
+	}
+
+	publishTicketEvent(ctx, redis.TicketEventClaimed, ticket, &claimerId, actorId)
+	notifyTicket(ctx, botContext, ticket, title, description)
+	ctx.Status(http.StatusNoContent)
+}
+
+// notifyTicket posts a change to the ticket channel. The change has already been applied by this point, so a
+// failure to notify is logged rather than returned to the user.
+func notifyTicket(ctx *gin.Context, botContext *botcontext.BotContext, ticket database.Ticket, title, description string) {
+	if err := utils.SendTicketNotice(ctx, botContext, ticket, title, description); err != nil {
+		log.Logger.Warn(
+			"Failed to send ticket notification",
+			zap.Error(err),
+			zap.Uint64("guild_id", ticket.GuildId),
+			zap.Int("ticket_id", ticket.Id),
+		)
+	}
+}
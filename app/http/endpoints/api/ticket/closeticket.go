@@ -70,5 +70,8 @@ func CloseTicket(c *gin.Context) {
 		return
 	}
 
+	// The worker closes the ticket asynchronously, but the request has been accepted, so remove it from live lists now
+	publishTicketEvent(c, redis.TicketEventClosed, ticket, nil, userId)
+
 	c.JSON(200, utils.SuccessResponse)
 }
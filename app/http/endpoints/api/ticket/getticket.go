@@ -77,10 +77,17 @@ func GetTicket(c *gin.Context) {
 		return
 	}
 
+	notes, err := dbclient.Dashboard.TicketNotes.GetByTicket(c, guildId, ticket.Id)
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, app.NewServerError(err))
+		return
+	}
+
 	c.JSON(200, gin.H{
 		"success":  true,
 		"ticket":   ticket,
 		"messages": messages,
+		"notes":    notes,
 	})
 }
 
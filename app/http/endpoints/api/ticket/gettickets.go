@@ -6,9 +6,13 @@ import (
 
 	"github.com/TicketsBot-cloud/dashboard/app"
 	"github.com/TicketsBot-cloud/dashboard/database"
+	"github.com/TicketsBot-cloud/dashboard/log"
+	"github.com/TicketsBot-cloud/dashboard/redis"
 	"github.com/TicketsBot-cloud/dashboard/rpc/cache"
+	"github.com/TicketsBot-cloud/dashboard/utils/types"
 	"github.com/gin-gonic/gin"
 	"github.com/rxdn/gdl/objects/user"
+	"go.uber.org/zap"
 )
 
 type (
@@ -20,13 +24,14 @@ type (
 	}
 
 	ticketData struct {
-		TicketId            int        `json:"id"`
-		PanelId             *int       `json:"panel_id"`
-		UserId              uint64     `json:"user_id,string"`
-		ClaimedBy           *uint64    `json:"claimed_by,string"`
-		OpenedAt            time.Time  `json:"opened_at"`
-		LastResponseTime    *time.Time `json:"last_response_time"`
-		LastResponseIsStaff *bool      `json:"last_response_is_staff"`
+		TicketId            int                     `json:"id"`
+		PanelId             *int                    `json:"panel_id"`
+		UserId              uint64                  `json:"user_id,string"`
+		ClaimedBy           *uint64                 `json:"claimed_by,string"`
+		OpenedAt            time.Time               `json:"opened_at"`
+		LastResponseTime    *time.Time              `json:"last_response_time"`
+		LastResponseIsStaff *bool                   `json:"last_response_is_staff"`
+		Viewers             types.UInt64StringSlice `json:"viewers"`
 	}
 )
 
@@ -51,6 +56,19 @@ func GetTickets(c *gin.Context) {
 		panelTitles[panel.PanelId] = panel.Title
 	}
 
+	// Staff currently viewing each ticket through live-chat
+	ticketIds := make([]int, len(tickets))
+	for i, ticket := range tickets {
+		ticketIds[i] = ticket.Id
+	}
+
+	// Viewers are only informational, so do not fail the ticket list if they cannot be retrieved
+	viewers, err := redis.Client.GetTicketsViewers(c, guildId, ticketIds)
+	if err != nil {
+		log.Logger.Warn("Failed to retrieve ticket viewers", zap.Error(err), zap.Uint64("guild_id", guildId))
+		viewers = make(map[int][]uint64)
+	}
+
 	// Get user objects
 	userIds := make([]uint64, 0, int(float32(len(tickets))*1.5))
 	for _, ticket := range tickets {
@@ -59,6 +77,8 @@ func GetTickets(c *gin.Context) {
 		if ticket.ClaimedBy != nil {
 			userIds = append(userIds, *ticket.ClaimedBy)
 		}
+
+		userIds = append(userIds, viewers[ticket.Id]...)
 	}
 
 	users, err := cache.Instance.GetUsers(c, userIds)
@@ -77,6 +97,7 @@ func GetTickets(c *gin.Context) {
 			OpenedAt:            ticket.OpenTime,
 			LastResponseTime:    ticket.LastMessageTime,
 			LastResponseIsStaff: ticket.UserIsStaff,
+			Viewers:             viewers[ticket.Id],
 		}
 	}
 
@@ -1,27 +1,52 @@
 package livechat
 
 import (
+	"sync"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
-	"time"
 )
 
 type Client struct {
 	Manager       *SocketManager
-	Ws            *websocket.Conn
+	Ws            *websocket.Conn // nil for SSE clients
 	RequestCtx    *gin.Context
 	Authenticated bool
+	UserId        uint64
 	GuildId       uint64
-	TicketId      int
+	TicketId      int // 0 if the client is subscribed to the guild's ticket list, rather than a single ticket
+	sessionId     string
+	trackPresence bool
+	lastTyping    time.Time
+	revalidate    chan struct{}
 	tx            chan any
-	flush         chan chan struct{}
+	done          chan struct{}
+	closeOnce     sync.Once
+
+	// mu guards the state below, which is shared between the read loop and the socket manager
+	mu            sync.Mutex
+	resuming      bool
+	pending       []pendingMessage
+	lastMessageId uint64
+	isStaff       bool // Only staff receive internal notes
 }
 
+// flushRequest is queued in-line with regular messages, so that it is only acknowledged once every message queued
+// before it has been written to the socket.
+type flushRequest chan struct{}
+
 const (
 	messageSizeLimit   = 1024 * 32
 	keepaliveFrequency = 45 * time.Second
 	keepaliveTimeout   = 60 * time.Second
 	writeTimeout       = 10 * time.Second
+	flushTimeout       = time.Second
+
+	// outboundQueueSize is the number of messages that may be waiting to be written to a client. If a client falls this
+	// far behind, it is treated as a slow consumer and disconnected, so that it can never block delivery to others.
+	outboundQueueSize = 256
 )
 
 func NewClient(manager *SocketManager, ws *websocket.Conn, c *gin.Context, guildId uint64, ticketId int) *Client {
@@ -32,13 +57,32 @@ func NewClient(manager *SocketManager, ws *websocket.Conn, c *gin.Context, guild
 		Authenticated: false,
 		GuildId:       guildId,
 		TicketId:      ticketId,
-		tx:            make(chan any),
-		flush:         make(chan chan struct{}),
+		sessionId:     uuid.NewString(),
+		revalidate:    make(chan struct{}, 1),
+		tx:            make(chan any, outboundQueueSize),
+		done:          make(chan struct{}),
 	}
 }
 
+func (c *Client) IsGuildScoped() bool {
+	return c.TicketId == 0
+}
+
+// Close stops the write loop. It is safe to call multiple times, and from any goroutine.
 func (c *Client) Close() {
-	close(c.tx)
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+// Disconnect closes the underlying connection, which causes the read loop to exit and unregister the client.
+func (c *Client) Disconnect() {
+	c.Close()
+
+	// SSE clients have no websocket, and finish as soon as the write loop exits
+	if c.Ws != nil {
+		_ = c.Ws.Close()
+	}
 }
 
 func (c *Client) StartReadLoop() error {
@@ -65,10 +109,8 @@ func (c *Client) StartReadLoop() error {
 		}
 
 		if !c.Authenticated && event.Type != EventTypeAuth {
-			if err := c.Ws.WriteJSON(NewErrorMessage("Unauthorized")); err != nil {
-				return err
-			}
-
+			c.Write(NewErrorMessage("Unauthorized"))
+			c.Flush()
 			return nil
 		}
 
@@ -82,31 +124,72 @@ func (c *Client) StartReadLoop() error {
 	}
 }
 
-func (c *Client) Write(msg any) {
-	c.tx <- msg
+// Write queues a message to be sent to the client without blocking. If the client's outbound queue is full, the
+// message is dropped and the client is disconnected. Returns whether the message was queued.
+func (c *Client) Write(msg any) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+
+	select {
+	case c.tx <- msg:
+		queueDepth.Observe(float64(len(c.tx)))
+		return true
+	default:
+		droppedMessages.Inc()
+		slowConsumerDisconnects.Inc()
+		c.Disconnect()
+		return false
+	}
+}
+
+// deliverEvent writes an event to the client if it has authenticated
+func (c *Client) deliverEvent(event Event) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.Authenticated {
+		return false
+	}
+
+	return c.Write(event)
+}
+
+// deliverStaffEvent delivers an event that must only be seen by staff, such as an internal note
+func (c *Client) deliverStaffEvent(event Event) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.Authenticated || !c.isStaff {
+		return false
+	}
+
+	return c.Write(event)
 }
 
 func (c *Client) StartWriteLoop() error {
 	ticker := time.NewTicker(keepaliveFrequency)
 	defer func() {
 		ticker.Stop()
-		_ = c.Ws.Close()
+		c.Disconnect()
 	}()
 
 	for {
 		select {
-		case message, ok := <-c.tx:
+		case message := <-c.tx:
+			if flush, ok := message.(flushRequest); ok {
+				close(flush)
+				continue
+			}
+
 			if err := c.Ws.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
 				return err
 			}
 
-			if !ok { // Channel was closed
-				_ = c.Ws.WriteMessage(websocket.CloseMessage, []byte{})
-				return nil
-			} else {
-				if err := c.Ws.WriteJSON(message); err != nil {
-					return err
-				}
+			if err := c.Ws.WriteJSON(message); err != nil {
+				return err
 			}
 		case <-ticker.C:
 			if err := c.Ws.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
@@ -116,21 +199,24 @@ func (c *Client) StartWriteLoop() error {
 			if err := c.Ws.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return err
 			}
-		case ch := <-c.flush: // TODO: Channel order is random, there is a race condition here
-			ch <- struct{}{}
+		case <-c.done:
+			_ = c.Ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+			_ = c.Ws.WriteMessage(websocket.CloseMessage, []byte{})
+			return nil
 		}
 	}
 }
 
+// Flush blocks until every message queued before the call has been written, or until flushTimeout has elapsed.
 func (c *Client) Flush() {
-	ch := make(chan struct{})
-	c.flush <- ch
+	ch := make(flushRequest)
+	if !c.Write(ch) {
+		return
+	}
 
-	timer := time.After(time.Second)
 	select {
 	case <-ch:
-		return
-	case <-timer:
-		return
+	case <-c.done:
+	case <-time.After(flushTimeout):
 	}
 }
@@ -2,6 +2,9 @@ package livechat
 
 import (
 	"encoding/json"
+	"time"
+
+	"github.com/TicketsBot-cloud/dashboard/utils/types"
 )
 
 type (
@@ -10,10 +13,53 @@ type (
 	Event struct {
 		Type EventType       `json:"type"`
 		Data json.RawMessage `json:"data,omitempty"`
+
+		// Set for relayed messages, used as the SSE event ID so that EventSource can resume the stream
+		messageId uint64
 	}
 
 	AuthData struct {
-		Token string `json:"token"`
+		Token         string  `json:"token"`
+		LastMessageId *uint64 `json:"last_message_id,string,omitempty"`
+	}
+
+	SendMessageData struct {
+		Nonce   string `json:"nonce"`
+		Content string `json:"content"`
+	}
+
+	MessageDeleteData struct {
+		MessageId uint64 `json:"message_id,string"`
+	}
+
+	AckData struct {
+		Nonce string `json:"nonce"`
+	}
+
+	NonceErrorData struct {
+		Nonce string `json:"nonce"`
+		Error string `json:"error"`
+	}
+
+	TicketEventData struct {
+		TicketId         int        `json:"id"`
+		PanelId          *int       `json:"panel_id,omitempty"`
+		UserId           uint64     `json:"user_id,string"`
+		ClaimedBy        *uint64    `json:"claimed_by,string,omitempty"`
+		ActorId          *uint64    `json:"actor_id,string,omitempty"`
+		Timestamp        time.Time  `json:"timestamp"`
+		LastResponseTime *time.Time `json:"last_response_time,omitempty"`
+		LastAuthorId     *uint64    `json:"last_author_id,string,omitempty"`
+	}
+
+	ViewersData struct {
+		TicketId int                     `json:"ticket_id"`
+		Viewers  types.UInt64StringSlice `json:"viewers"`
+	}
+
+	TypingData struct {
+		TicketId int    `json:"ticket_id"`
+		UserId   uint64 `json:"user_id,string"`
 	}
 
 	ErrorMessage struct {
@@ -22,11 +68,42 @@ type (
 )
 
 const (
-	EventTypeAuth          EventType = "auth"
-	EventTypeAuthenticated EventType = "authenticated"
-	EventTypeMessage       EventType = "message"
+	EventTypeAuth           EventType = "auth"
+	EventTypeAuthenticated  EventType = "authenticated"
+	EventTypeUnauthorized   EventType = "unauthorized"
+	EventTypeMessage        EventType = "message"
+	EventTypeMessageUpdate  EventType = "message_update"
+	EventTypeMessageDelete  EventType = "message_delete"
+	EventTypeResyncRequired EventType = "resync_required"
+	EventTypeSendMessage    EventType = "send_message"
+	EventTypeAck            EventType = "ack"
+	EventTypeError          EventType = "error"
+	EventTypeTyping         EventType = "typing"
+	EventTypeViewers        EventType = "viewers"
+	EventTypeNoteCreated    EventType = "note_created"
+	EventTypeNoteUpdated    EventType = "note_updated"
+	EventTypeNoteDeleted    EventType = "note_deleted"
+
+	// Events sent to guild-scoped clients
+	EventTypeTicketOpened      EventType = "ticket_opened"
+	EventTypeTicketClosed      EventType = "ticket_closed"
+	EventTypeTicketClaimed     EventType = "ticket_claimed"
+	EventTypeTicketUnclaimed   EventType = "ticket_unclaimed"
+	EventTypeTicketLastMessage EventType = "ticket_last_message"
 )
 
 func NewErrorMessage(message string) ErrorMessage {
 	return ErrorMessage{message}
 }
+
+func NewEvent(eventType EventType, data any) (Event, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+
+	return Event{
+		Type: eventType,
+		Data: encoded,
+	}, nil
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/TicketsBot-cloud/common/permission"
 	"github.com/TicketsBot-cloud/common/premium"
 	"github.com/TicketsBot-cloud/dashboard/botcontext"
 	"github.com/TicketsBot-cloud/dashboard/config"
@@ -15,6 +16,7 @@ import (
 	"github.com/TicketsBot-cloud/dashboard/internal/api"
 	"github.com/TicketsBot-cloud/dashboard/rpc"
 	"github.com/TicketsBot-cloud/dashboard/utils"
+	"github.com/TicketsBot-cloud/database"
 	"github.com/golang-jwt/jwt"
 )
 
@@ -24,14 +26,31 @@ func (c *Client) HandleEvent(event Event) error {
 		var data AuthData
 		if err := json.Unmarshal(event.Data, &data); err != nil {
 			c.Write(NewErrorMessage("Malformed event payload"))
-			_ = c.Ws.Close()
 			c.Flush()
+			_ = c.Ws.Close()
 			return err
 		}
 
 		if err := c.handleAuthEvent(data); err != nil {
 			return err
 		}
+	case EventTypeSendMessage:
+		var data SendMessageData
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			c.writeNonceError("", "Malformed event payload")
+			return nil
+		}
+
+		if c.IsGuildScoped() {
+			c.writeNonceError(data.Nonce, "Messages can only be sent to a ticket")
+			return nil
+		}
+
+		c.handleSendMessageEvent(data)
+	case EventTypeTyping:
+		if !c.IsGuildScoped() {
+			c.handleTypingEvent()
+		}
 	}
 
 	return nil
@@ -68,47 +87,104 @@ func (c *Client) handleAuthEvent(data AuthData) error {
 		return api.NewErrorWithMessage(http.StatusUnauthorized, err, "Invalid token data")
 	}
 
+	return c.authenticate(userId, data.LastMessageId)
+}
+
+// authenticate verifies that the user may receive events for the client's ticket or guild, and then starts
+// delivering them, beginning with any messages missed since lastMessageId. Shared by the websocket and SSE transports.
+func (c *Client) authenticate(userId uint64, lastMessageId *uint64) error {
+	var isStaff bool
+	if c.IsGuildScoped() {
+		if err := c.verifyGuildAccess(userId); err != nil {
+			return err
+		}
+	} else {
+		ticket, err := c.verifyTicketAccess(userId)
+		if err != nil {
+			return err
+		}
+
+		// Only staff are listed as viewers, not the ticket opener
+		isStaff = ticket.UserId != userId
+		c.trackPresence = isStaff
+	}
+
+	// Hold back live messages until any missed messages have been replayed, so that they are delivered in order
+	resuming := lastMessageId != nil && !c.IsGuildScoped()
+
+	c.mu.Lock()
+	c.Authenticated = true
+	c.UserId = userId
+	c.isStaff = isStaff
+	c.resuming = resuming
+	c.Write(Event{
+		Type: EventTypeAuthenticated,
+	})
+	c.mu.Unlock()
+
+	if resuming {
+		c.resume(*lastMessageId)
+	}
+
+	if c.trackPresence {
+		c.startPresence()
+	}
+
+	c.startRevalidation()
+
+	return nil
+}
+
+func (c *Client) verifyTicketAccess(userId uint64) (database.Ticket, error) {
 	// Get the ticket
 	ticket, err := dbclient.Client.Tickets.Get(context.Background(), c.TicketId, c.GuildId)
 	if err != nil {
-		return api.NewErrorWithMessage(http.StatusInternalServerError, err, "Error retrieving ticket data")
+		return database.Ticket{}, api.NewErrorWithMessage(http.StatusInternalServerError, err, "Error retrieving ticket data")
 	}
 
 	if ticket.Id == 0 || ticket.GuildId == 0 || ticket.GuildId != c.GuildId {
-		return api.NewErrorWithMessage(http.StatusNotFound, err, "Ticket not found")
+		return database.Ticket{}, api.NewErrorWithMessage(http.StatusNotFound, err, "Ticket not found")
 	}
 
 	// Verify the user has permissions to be here
 	hasPermission, requestErr := utils.HasPermissionToViewTicket(context.Background(), c.GuildId, userId, ticket)
 	if requestErr != nil {
-		return api.NewErrorWithMessage(http.StatusInternalServerError, err, "Error retrieving permission data")
+		return database.Ticket{}, requestErr
 	}
 
 	if !hasPermission {
-		return api.NewErrorWithMessage(http.StatusForbidden, err, "You do not have permission to view this ticket")
+		return database.Ticket{}, api.NewErrorWithMessage(http.StatusForbidden, err, "You do not have permission to view this ticket")
 	}
 
 	// Check premium
 	botContext, err := botcontext.ContextForGuild(c.GuildId)
 	if err != nil {
-		return api.NewErrorWithMessage(http.StatusInternalServerError, err, "Error retrieving bot context")
+		return database.Ticket{}, api.NewErrorWithMessage(http.StatusInternalServerError, err, "Error retrieving bot context")
 	}
 
 	// Verify the guild is premium
 	premiumTier, err := rpc.PremiumClient.GetTierByGuildId(context.Background(), c.GuildId, true, botContext.Token, botContext.RateLimiter)
 	if err != nil {
-		return api.NewErrorWithMessage(http.StatusInternalServerError, err, "Error retrieving premium tier")
+		return database.Ticket{}, api.NewErrorWithMessage(http.StatusInternalServerError, err, "Error retrieving premium tier")
 	}
 
 	if premiumTier == premium.None {
-		return api.NewErrorWithMessage(http.StatusPaymentRequired, err, "Live-chat requires premium to use")
+		return database.Ticket{}, api.NewErrorWithMessage(http.StatusPaymentRequired, err, "Live-chat requires premium to use")
 	}
 
-	c.Authenticated = true
+	return ticket, nil
+}
 
-	c.Write(Event{
-		Type: EventTypeAuthenticated,
-	})
+// verifyGuildAccess checks that the user may view the guild's ticket list, which matches the GET /tickets route
+func (c *Client) verifyGuildAccess(userId uint64) error {
+	permissionLevel, err := utils.GetPermissionLevel(context.Background(), c.GuildId, userId)
+	if err != nil {
+		return api.NewErrorWithMessage(http.StatusInternalServerError, err, "Error retrieving permission data")
+	}
+
+	if permissionLevel < permission.Support {
+		return api.NewErrorWithMessage(http.StatusForbidden, errors.New("insufficient permission level"), "You do not have permission to view this guild's tickets")
+	}
 
 	return nil
 }
@@ -43,3 +43,25 @@ func GetLiveChatHandler(sm *SocketManager) gin.HandlerFunc {
 		go client.StartWriteLoop()
 	}
 }
+
+// GetTicketListHandler upgrades to a guild-scoped socket, which receives ticket list events for every ticket in the
+// guild, rather than the messages of a single ticket.
+func GetTicketListHandler(sm *SocketManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			return
+		}
+
+		guildId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(400, utils.ErrorJson(err))
+			return
+		}
+
+		client := NewClient(sm, conn, c, guildId, 0)
+		sm.register <- client
+		go client.StartReadLoop()
+		go client.StartWriteLoop()
+	}
+}
@@ -3,8 +3,11 @@ package livechat
 import (
 	"encoding/json"
 	"strconv"
+	"time"
 
 	"github.com/TicketsBot-cloud/common/chatrelay"
+	"github.com/TicketsBot-cloud/dashboard/redis"
+	"github.com/TicketsBot-cloud/database"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -23,23 +26,76 @@ var (
 		Name:      "livechat_websocket_messages",
 		Help:      "The number of messages relayed over live-chat websockets",
 	}, []string{"guild_id"})
+
+	queueDepth = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "tickets",
+		Subsystem: "api",
+		Name:      "livechat_websocket_queue_depth",
+		Help:      "The number of messages waiting to be written to a live-chat websocket, observed on each enqueue",
+		Buckets:   []float64{0, 1, 2, 4, 8, 16, 32, 64, 128, outboundQueueSize},
+	})
+
+	droppedMessages = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tickets",
+		Subsystem: "api",
+		Name:      "livechat_websocket_dropped_messages",
+		Help:      "The number of live-chat messages dropped because the client's outbound queue was full",
+	})
+
+	slowConsumerDisconnects = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tickets",
+		Subsystem: "api",
+		Name:      "livechat_websocket_slow_consumer_disconnects",
+		Help:      "The number of live-chat websockets disconnected for not keeping up with their outbound queue",
+	})
+
+	permissionRevocations = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tickets",
+		Subsystem: "api",
+		Name:      "livechat_permission_revocations",
+		Help:      "The number of live-chat websockets closed because the user no longer has permission",
+	})
 )
 
+// The worker does not publish ticket opens, so a ticket is announced as opened when the first message relayed for it
+// arrives within this long of it opening, which is usually its welcome message. Older tickets are assumed to already
+// be in the client's list.
+const openedAnnounceWindow = 5 * time.Minute
+
 type (
 	SocketManager struct {
-		clients    map[uint64][]*Client // Remember: A client might not be authenticated!
-		messages   chan chatrelay.MessageData
-		register   chan *Client
-		unregister chan *Client
+		clients        map[uint64][]*Client    // Remember: A client might not be authenticated!
+		announcedOpens map[ticketKey]time.Time // Open time of each ticket recently announced as opened
+		messages       chan chatrelay.MessageData
+		updates        chan chatrelay.MessageData
+		deletes        chan redis.MessageDeleteData
+		ticketEvents   chan redis.TicketEvent
+		presence       chan redis.PresenceEvent
+		notes          chan redis.TicketNoteEvent
+		revalidate     chan redis.PermissionRevalidation
+		register       chan *Client
+		unregister     chan *Client
+	}
+
+	ticketKey struct {
+		GuildId  uint64
+		TicketId int
 	}
 )
 
 func NewSocketManager() *SocketManager {
 	return &SocketManager{
-		clients:    map[uint64][]*Client{},
-		messages:   make(chan chatrelay.MessageData),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
+		clients:        map[uint64][]*Client{},
+		announcedOpens: make(map[ticketKey]time.Time),
+		messages:       make(chan chatrelay.MessageData),
+		updates:        make(chan chatrelay.MessageData),
+		deletes:        make(chan redis.MessageDeleteData),
+		ticketEvents:   make(chan redis.TicketEvent),
+		presence:       make(chan redis.PresenceEvent),
+		notes:          make(chan redis.TicketNoteEvent),
+		revalidate:     make(chan redis.PermissionRevalidation),
+		register:       make(chan *Client),
+		unregister:     make(chan *Client),
 	}
 }
 
@@ -84,8 +140,32 @@ func (sm *SocketManager) Run() {
 				continue // TODO: Warn
 			}
 
+			event := Event{
+				Type: EventTypeMessage,
+				Data: encoded,
+			}
+
+			lastMessageEvent, err := NewEvent(EventTypeTicketLastMessage, TicketEventData{
+				TicketId:         msg.Ticket.Id,
+				PanelId:          msg.Ticket.PanelId,
+				UserId:           msg.Ticket.UserId,
+				Timestamp:        msg.Message.Timestamp,
+				LastResponseTime: &msg.Message.Timestamp,
+				LastAuthorId:     &msg.Message.Author.Id,
+			})
+			if err != nil {
+				continue // TODO: Warn
+			}
+
+			openedEvent, announceOpen := sm.newOpenedEvent(msg.Ticket, time.Now())
+
 			for _, client := range guildClients {
-				if !client.Authenticated {
+				if client.IsGuildScoped() {
+					if announceOpen {
+						client.deliverEvent(openedEvent)
+					}
+
+					client.deliverEvent(lastMessageEvent)
 					continue
 				}
 
@@ -94,12 +174,89 @@ func (sm *SocketManager) Run() {
 					continue
 				}
 
-				websocketMessages.WithLabelValues(strconv.FormatUint(client.GuildId, 10)).Inc()
+				if client.deliverMessage(msg.Message.Id, event) {
+					websocketMessages.WithLabelValues(strconv.FormatUint(client.GuildId, 10)).Inc()
+				}
+			}
+		case msg := <-sm.updates:
+			event, err := NewEvent(EventTypeMessageUpdate, msg.Message)
+			if err != nil {
+				continue // TODO: Warn
+			}
+
+			sm.patchTicket(msg.Ticket, event)
+		case msg := <-sm.deletes:
+			event, err := NewEvent(EventTypeMessageDelete, MessageDeleteData{
+				MessageId: msg.MessageId,
+			})
+			if err != nil {
+				continue // TODO: Warn
+			}
 
-				client.Write(Event{
-					Type: EventTypeMessage,
-					Data: encoded,
-				})
+			sm.patchTicket(msg.Ticket, event)
+		case ticketEvent := <-sm.ticketEvents:
+			guildClients, ok := sm.clients[ticketEvent.GuildId]
+			if !ok || len(guildClients) == 0 {
+				continue
+			}
+
+			event, ok := newTicketListEvent(ticketEvent)
+			if !ok {
+				continue
+			}
+
+			for _, client := range guildClients {
+				if client.IsGuildScoped() {
+					client.deliverEvent(event)
+				}
+			}
+		case presenceEvent := <-sm.presence:
+			guildClients, ok := sm.clients[presenceEvent.GuildId]
+			if !ok || len(guildClients) == 0 {
+				continue
+			}
+
+			event, ok := newPresenceEvent(presenceEvent)
+			if !ok {
+				continue
+			}
+
+			for _, client := range guildClients {
+				// The ticket list shows who is handling each ticket, but does not need typing indicators
+				if client.IsGuildScoped() {
+					if presenceEvent.Type == redis.PresenceEventViewers {
+						client.deliverEvent(event)
+					}
+
+					continue
+				}
+
+				if client.TicketId != presenceEvent.TicketId {
+					continue
+				}
+
+				// Presence is only shown to staff, so that the ticket opener cannot see who is viewing their ticket
+				if presenceEvent.Type == redis.PresenceEventTyping {
+					client.deliverTypingEvent(presenceEvent.UserId, event)
+				} else {
+					client.deliverStaffEvent(event)
+				}
+			}
+		case noteEvent := <-sm.notes:
+			event, ok := newNoteEvent(noteEvent)
+			if !ok {
+				continue
+			}
+
+			for _, client := range sm.clients[noteEvent.Note.GuildId] {
+				// Notes are internal, so must never be delivered to the ticket opener
+				if !client.IsGuildScoped() && client.TicketId == noteEvent.Note.TicketId {
+					client.deliverStaffEvent(event)
+				}
+			}
+		case revalidation := <-sm.revalidate:
+			for _, client := range sm.clients[revalidation.GuildId] {
+				client.RequestRevalidation(revalidation.UserId)
 			}
 		}
 	}
@@ -108,3 +265,117 @@ func (sm *SocketManager) Run() {
 func (sm *SocketManager) BroadcastMessage(message chatrelay.MessageData) {
 	sm.messages <- message
 }
+
+func (sm *SocketManager) BroadcastMessageUpdate(message chatrelay.MessageData) {
+	sm.updates <- message
+}
+
+func (sm *SocketManager) BroadcastMessageDelete(data redis.MessageDeleteData) {
+	sm.deletes <- data
+}
+
+// patchTicket must only be called from the Run goroutine
+func (sm *SocketManager) patchTicket(ticket database.Ticket, event Event) {
+	for _, client := range sm.clients[ticket.GuildId] {
+		if !client.IsGuildScoped() && client.TicketId == ticket.Id {
+			client.deliverMessagePatch(event)
+		}
+	}
+}
+
+func (sm *SocketManager) BroadcastTicketEvent(event redis.TicketEvent) {
+	sm.ticketEvents <- event
+}
+
+func (sm *SocketManager) BroadcastPresenceEvent(event redis.PresenceEvent) {
+	sm.presence <- event
+}
+
+func (sm *SocketManager) BroadcastTicketNoteEvent(event redis.TicketNoteEvent) {
+	sm.notes <- event
+}
+
+func (sm *SocketManager) RevalidatePermissions(data redis.PermissionRevalidation) {
+	sm.revalidate <- data
+}
+
+// newOpenedEvent returns the event announcing that a ticket has opened, if the ticket opened recently and has not
+// already been announced. Clients should treat the event as an upsert, as each API server announces tickets separately.
+func (sm *SocketManager) newOpenedEvent(ticket database.Ticket, now time.Time) (Event, bool) {
+	for key, openTime := range sm.announcedOpens {
+		if now.Sub(openTime) > openedAnnounceWindow {
+			delete(sm.announcedOpens, key)
+		}
+	}
+
+	if !ticket.Open || now.Sub(ticket.OpenTime) > openedAnnounceWindow {
+		return Event{}, false
+	}
+
+	key := ticketKey{GuildId: ticket.GuildId, TicketId: ticket.Id}
+	if _, ok := sm.announcedOpens[key]; ok {
+		return Event{}, false
+	}
+
+	event, err := NewEvent(EventTypeTicketOpened, TicketEventData{
+		TicketId:  ticket.Id,
+		PanelId:   ticket.PanelId,
+		UserId:    ticket.UserId,
+		Timestamp: ticket.OpenTime,
+	})
+	if err != nil {
+		return Event{}, false
+	}
+
+	sm.announcedOpens[key] = ticket.OpenTime
+	return event, true
+}
+
+func newTicketListEvent(ticketEvent redis.TicketEvent) (Event, bool) {
+	var eventType EventType
+	switch ticketEvent.Type {
+	case redis.TicketEventClosed:
+		eventType = EventTypeTicketClosed
+	case redis.TicketEventClaimed:
+		eventType = EventTypeTicketClaimed
+	case redis.TicketEventUnclaimed:
+		eventType = EventTypeTicketUnclaimed
+	default:
+		return Event{}, false
+	}
+
+	event, err := NewEvent(eventType, TicketEventData{
+		TicketId:  ticketEvent.TicketId,
+		PanelId:   ticketEvent.PanelId,
+		UserId:    ticketEvent.UserId,
+		ClaimedBy: ticketEvent.ClaimedBy,
+		ActorId:   ticketEvent.ActorId,
+		Timestamp: ticketEvent.Timestamp,
+	})
+	if err != nil {
+		return Event{}, false
+	}
+
+	return event, true
+}
+
+func newNoteEvent(noteEvent redis.TicketNoteEvent) (Event, bool) {
+	var eventType EventType
+	switch noteEvent.Type {
+	case redis.TicketNoteCreated:
+		eventType = EventTypeNoteCreated
+	case redis.TicketNoteUpdated:
+		eventType = EventTypeNoteUpdated
+	case redis.TicketNoteDeleted:
+		eventType = EventTypeNoteDeleted
+	default:
+		return Event{}, false
+	}
+
+	event, err := NewEvent(eventType, noteEvent.Note)
+	if err != nil {
+		return Event{}, false
+	}
+
+	return event, true
+}
@@ -0,0 +1,277 @@
+package livechat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TicketsBot-cloud/common/chatrelay"
+	"github.com/TicketsBot-cloud/dashboard/utils"
+	"github.com/TicketsBot-cloud/database"
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/rxdn/gdl/objects/channel/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testGuildId  uint64 = 1
+	testTicketId int    = 1
+)
+
+// newTestConn returns the server side of a websocket connection, and the peer that would usually be the browser.
+func newTestConn(t *testing.T) (*websocket.Conn, *websocket.Conn) {
+	t.Helper()
+
+	serverConns := make(chan *websocket.Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		require.NoError(t, err)
+		serverConns <- conn
+	}))
+	t.Cleanup(server.Close)
+
+	peer, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = peer.Close()
+	})
+
+	return <-serverConns, peer
+}
+
+func newTestClient(t *testing.T, sm *SocketManager) (*Client, *websocket.Conn) {
+	t.Helper()
+
+	conn, peer := newTestConn(t)
+
+	client := NewClient(sm, conn, nil, testGuildId, testTicketId)
+	client.Authenticated = true
+
+	return client, peer
+}
+
+func testMessage(id uint64) chatrelay.MessageData {
+	return chatrelay.MessageData{
+		Ticket: database.Ticket{
+			Id:      testTicketId,
+			GuildId: testGuildId,
+		},
+		Message: message.Message{
+			Id: id,
+		},
+	}
+}
+
+func counterValue(t *testing.T, counter prometheus.Counter) float64 {
+	t.Helper()
+
+	var metric dto.Metric
+	require.NoError(t, counter.Write(&metric))
+
+	return metric.GetCounter().GetValue()
+}
+
+func broadcastWithTimeout(t *testing.T, sm *SocketManager, msg chatrelay.MessageData) {
+	t.Helper()
+
+	select {
+	case sm.messages <- msg:
+	case <-time.After(time.Second):
+		t.Fatal("fan-out blocked")
+	}
+}
+
+func TestSlowConsumerDoesNotBlockFanOut(t *testing.T) {
+	sm := NewSocketManager()
+	go sm.Run()
+
+	// The write loop is never started for the stuck client, so its queue is never drained
+	stuck, _ := newTestClient(t, sm)
+	healthy, healthyPeer := newTestClient(t, sm)
+	go healthy.StartWriteLoop()
+
+	sm.register <- stuck
+	sm.register <- healthy
+
+	droppedBefore := counterValue(t, droppedMessages)
+	disconnectsBefore := counterValue(t, slowConsumerDisconnects)
+
+	messageCount := outboundQueueSize + 10
+	received := make(chan Event, messageCount)
+	go func() {
+		for {
+			var event Event
+			if err := healthyPeer.ReadJSON(&event); err != nil {
+				return
+			}
+
+			received <- event
+		}
+	}()
+
+	// Wait for each message to be delivered before sending the next, so that only the stuck client falls behind
+	for i := 0; i < messageCount; i++ {
+		broadcastWithTimeout(t, sm, testMessage(uint64(i+1)))
+
+		select {
+		case event := <-received:
+			assert.Equal(t, EventTypeMessage, event.Type)
+		case <-time.After(time.Second * 5):
+			t.Fatalf("healthy client only received %d of %d messages", i, messageCount)
+		}
+	}
+
+	select {
+	case <-stuck.done:
+	default:
+		t.Fatal("stuck client was not disconnected")
+	}
+
+	assert.Equal(t, droppedBefore+1, counterValue(t, droppedMessages))
+	assert.Equal(t, disconnectsBefore+1, counterValue(t, slowConsumerDisconnects))
+}
+
+func TestWriteDoesNotBlockWhenQueueFull(t *testing.T) {
+	client, _ := newTestClient(t, NewSocketManager())
+
+	for i := 0; i < outboundQueueSize; i++ {
+		assert.True(t, client.Write(i))
+	}
+
+	done := make(chan bool)
+	go func() {
+		done <- client.Write(outboundQueueSize)
+	}()
+
+	select {
+	case queued := <-done:
+		assert.False(t, queued)
+	case <-time.After(time.Second):
+		t.Fatal("write blocked on full queue")
+	}
+}
+
+func TestWriteAfterClose(t *testing.T) {
+	client, _ := newTestClient(t, NewSocketManager())
+	client.Close()
+	client.Close()
+
+	assert.False(t, client.Write("message"))
+}
+
+func TestFlushWaitsForQueuedMessages(t *testing.T) {
+	client, peer := newTestClient(t, NewSocketManager())
+	go client.StartWriteLoop()
+
+	messageCount := 50
+	for i := 0; i < messageCount; i++ {
+		require.True(t, client.Write(NewErrorMessage("message")))
+	}
+
+	flushed := make(chan struct{})
+	go func() {
+		client.Flush()
+		close(flushed)
+	}()
+
+	select {
+	case <-flushed:
+	case <-time.After(flushTimeout * 2):
+		t.Fatal("flush did not return")
+	}
+
+	// Every message queued before the flush must already have been written to the socket
+	require.NoError(t, peer.SetReadDeadline(time.Now().Add(time.Second)))
+	for i := 0; i < messageCount; i++ {
+		var msg ErrorMessage
+		require.NoError(t, peer.ReadJSON(&msg))
+		assert.Equal(t, "message", msg.Error)
+	}
+}
+
+func TestFlushAfterCloseReturnsImmediately(t *testing.T) {
+	client, _ := newTestClient(t, NewSocketManager())
+	client.Close()
+
+	start := time.Now()
+	client.Flush()
+	assert.Less(t, time.Since(start), flushTimeout)
+}
+
+func TestDeliverMessageHeldWhileResuming(t *testing.T) {
+	client, _ := newTestClient(t, NewSocketManager())
+	client.resuming = true
+
+	assert.False(t, client.deliverMessage(5, Event{Type: EventTypeMessage}))
+	assert.Len(t, client.pending, 1)
+	assert.Len(t, client.tx, 0)
+}
+
+func TestDeliverMessageSkipsAlreadyReceived(t *testing.T) {
+	client, _ := newTestClient(t, NewSocketManager())
+	client.lastMessageId = 5
+
+	assert.False(t, client.deliverMessage(4, Event{Type: EventTypeMessage}))
+	assert.False(t, client.deliverMessage(5, Event{Type: EventTypeMessage}))
+	assert.True(t, client.deliverMessage(6, Event{Type: EventTypeMessage}))
+	assert.Len(t, client.tx, 1)
+}
+
+func TestRequestRevalidationMatchesUser(t *testing.T) {
+	client, _ := newTestClient(t, NewSocketManager())
+	client.UserId = 10
+
+	client.RequestRevalidation(utils.Ptr(uint64(11)))
+	assert.Len(t, client.revalidate, 0)
+
+	// Must not block when a check is already pending
+	client.RequestRevalidation(utils.Ptr(uint64(10)))
+	client.RequestRevalidation(nil)
+	assert.Len(t, client.revalidate, 1)
+}
+
+func TestMessagePatchNotSkipped(t *testing.T) {
+	client, _ := newTestClient(t, NewSocketManager())
+	client.lastMessageId = 5
+
+	assert.True(t, client.deliverMessagePatch(Event{Type: EventTypeMessageUpdate}))
+	assert.Len(t, client.tx, 1)
+}
+
+func TestOpenedEventAnnouncedOnce(t *testing.T) {
+	sm := NewSocketManager()
+	now := time.Now()
+
+	ticket := database.Ticket{
+		Id:       testTicketId,
+		GuildId:  testGuildId,
+		Open:     true,
+		OpenTime: now.Add(-time.Minute),
+	}
+
+	event, ok := sm.newOpenedEvent(ticket, now)
+	assert.True(t, ok)
+	assert.Equal(t, EventTypeTicketOpened, event.Type)
+
+	_, ok = sm.newOpenedEvent(ticket, now)
+	assert.False(t, ok)
+}
+
+func TestOpenedEventNotAnnouncedForOldTicket(t *testing.T) {
+	sm := NewSocketManager()
+	now := time.Now()
+
+	_, ok := sm.newOpenedEvent(database.Ticket{
+		Id:       testTicketId,
+		GuildId:  testGuildId,
+		Open:     true,
+		OpenTime: now.Add(-openedAnnounceWindow - time.Minute),
+	}, now)
+	assert.False(t, ok)
+	assert.Empty(t, sm.announcedOpens)
+}
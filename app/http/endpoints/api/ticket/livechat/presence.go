@@ -0,0 +1,120 @@
+package livechat
+
+import (
+	"time"
+
+	"github.com/TicketsBot-cloud/dashboard/log"
+	"github.com/TicketsBot-cloud/dashboard/redis"
+	"github.com/TicketsBot-cloud/dashboard/utils/types"
+	"go.uber.org/zap"
+)
+
+const (
+	presenceRefreshInterval = 30 * time.Second
+	typingThrottle          = 3 * time.Second
+)
+
+// startPresence lists the client as a viewer of the ticket until it disconnects. Must only be called once the client
+// has authenticated.
+func (c *Client) startPresence() {
+	if err := redis.Client.SetTicketPresence(redis.DefaultContext(), c.GuildId, c.TicketId, c.UserId, c.sessionId); err != nil {
+		log.Logger.Warn("Failed to set ticket presence", zap.Error(err), zap.Uint64("guild_id", c.GuildId), zap.Int("ticket_id", c.TicketId))
+		return
+	}
+
+	c.publishViewers()
+
+	go func() {
+		ticker := time.NewTicker(presenceRefreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := redis.Client.SetTicketPresence(redis.DefaultContext(), c.GuildId, c.TicketId, c.UserId, c.sessionId); err != nil {
+					log.Logger.Warn("Failed to refresh ticket presence", zap.Error(err), zap.Uint64("guild_id", c.GuildId), zap.Int("ticket_id", c.TicketId))
+				}
+			case <-c.done:
+				if err := redis.Client.RemoveTicketPresence(redis.DefaultContext(), c.GuildId, c.TicketId, c.UserId, c.sessionId); err != nil {
+					log.Logger.Warn("Failed to remove ticket presence", zap.Error(err), zap.Uint64("guild_id", c.GuildId), zap.Int("ticket_id", c.TicketId))
+				}
+
+				c.publishViewers()
+				return
+			}
+		}
+	}()
+}
+
+// publishViewers notifies every API replica of the current viewers of the ticket
+func (c *Client) publishViewers() {
+	viewers, err := redis.Client.GetTicketViewers(redis.DefaultContext(), c.GuildId, c.TicketId)
+	if err != nil {
+		log.Logger.Warn("Failed to fetch ticket viewers", zap.Error(err), zap.Uint64("guild_id", c.GuildId), zap.Int("ticket_id", c.TicketId))
+		return
+	}
+
+	if err := redis.Client.PublishPresenceEvent(redis.DefaultContext(), redis.PresenceEvent{
+		Type:     redis.PresenceEventViewers,
+		GuildId:  c.GuildId,
+		TicketId: c.TicketId,
+		Viewers:  viewers,
+	}); err != nil {
+		log.Logger.Warn("Failed to publish ticket viewers", zap.Error(err), zap.Uint64("guild_id", c.GuildId), zap.Int("ticket_id", c.TicketId))
+	}
+}
+
+func (c *Client) handleTypingEvent() {
+	if time.Since(c.lastTyping) < typingThrottle {
+		return
+	}
+
+	c.lastTyping = time.Now()
+
+	if err := redis.Client.PublishPresenceEvent(redis.DefaultContext(), redis.PresenceEvent{
+		Type:     redis.PresenceEventTyping,
+		GuildId:  c.GuildId,
+		TicketId: c.TicketId,
+		UserId:   c.UserId,
+	}); err != nil {
+		log.Logger.Warn("Failed to publish typing event", zap.Error(err), zap.Uint64("guild_id", c.GuildId), zap.Int("ticket_id", c.TicketId))
+	}
+}
+
+// deliverTypingEvent writes a typing event to a staff client, unless the client belongs to the user who is typing
+func (c *Client) deliverTypingEvent(userId uint64, event Event) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.Authenticated || !c.isStaff || c.UserId == userId {
+		return false
+	}
+
+	return c.Write(event)
+}
+
+func newPresenceEvent(presenceEvent redis.PresenceEvent) (Event, bool) {
+	var event Event
+	var err error
+
+	switch presenceEvent.Type {
+	case redis.PresenceEventViewers:
+		event, err = NewEvent(EventTypeViewers, ViewersData{
+			TicketId: presenceEvent.TicketId,
+			Viewers:  types.UInt64StringSlice(presenceEvent.Viewers),
+		})
+	case redis.PresenceEventTyping:
+		event, err = NewEvent(EventTypeTyping, TypingData{
+			TicketId: presenceEvent.TicketId,
+			UserId:   presenceEvent.UserId,
+		})
+	default:
+		return Event{}, false
+	}
+
+	if err != nil {
+		return Event{}, false
+	}
+
+	return event, true
+}
@@ -0,0 +1,127 @@
+package livechat
+
+import (
+	"context"
+
+	dbclient "github.com/TicketsBot-cloud/dashboard/database"
+	"github.com/TicketsBot-cloud/dashboard/log"
+	"github.com/TicketsBot-cloud/dashboard/redis"
+	"go.uber.org/zap"
+)
+
+type pendingMessage struct {
+	id    uint64
+	event Event
+	patch bool
+}
+
+// deliverMessage writes a relayed message to the client. While a resume replay is in progress, live messages are held
+// back, and any message the client has already received is skipped. Returns whether the message was written.
+func (c *Client) deliverMessage(id uint64, event Event) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.Authenticated {
+		return false
+	}
+
+	if c.resuming {
+		c.pending = append(c.pending, pendingMessage{id: id, event: event})
+		return false
+	}
+
+	return c.writeMessage(id, event)
+}
+
+// deliverMessagePatch writes an edit or deletion of an existing message to the client. Patches are held back during a
+// resume like new messages, but are never skipped, as they refer to messages the client may already have.
+func (c *Client) deliverMessagePatch(event Event) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.Authenticated {
+		return false
+	}
+
+	if c.resuming {
+		c.pending = append(c.pending, pendingMessage{event: event, patch: true})
+		return false
+	}
+
+	return c.Write(event)
+}
+
+// writeMessage must be called with mu held
+func (c *Client) writeMessage(id uint64, event Event) bool {
+	if id <= c.lastMessageId {
+		return false
+	}
+
+	c.lastMessageId = id
+	event.messageId = id
+	return c.Write(event)
+}
+
+// resume replays messages relayed since lastMessageId from the Redis history buffer, followed by any live messages
+// that arrived during the replay. If the replay may be missing messages or patches, the client is told to refetch.
+func (c *Client) resume(lastMessageId uint64) {
+	ctx := redis.DefaultContext()
+
+	messages, complete, err := redis.Client.GetLiveChatHistorySince(ctx, c.GuildId, c.TicketId, lastMessageId)
+	if err == nil && !complete && len(messages) == 0 {
+		complete, err = c.noMessagesSince(ctx, lastMessageId)
+	}
+
+	// Replayed messages include any edits, but edits and deletions of messages the client already has are not replayed
+	if err == nil && complete {
+		var missedPatch bool
+		missedPatch, err = redis.Client.MayHaveMissedLiveChatPatch(ctx, c.GuildId, c.TicketId, lastMessageId)
+		complete = !missedPatch
+	}
+
+	if err != nil {
+		log.Logger.Warn("Failed to fetch live-chat history", zap.Error(err), zap.Uint64("guild_id", c.GuildId), zap.Int("ticket_id", c.TicketId))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.resuming = false
+	c.lastMessageId = lastMessageId
+
+	if err != nil || !complete {
+		c.Write(Event{
+			Type: EventTypeResyncRequired,
+		})
+	}
+
+	for _, msg := range messages {
+		event, err := NewEvent(EventTypeMessage, msg)
+		if err != nil {
+			continue
+		}
+
+		c.writeMessage(msg.Id, event)
+	}
+
+	for _, msg := range c.pending {
+		if msg.patch {
+			c.Write(msg.event)
+		} else {
+			c.writeMessage(msg.id, msg.event)
+		}
+	}
+
+	c.pending = nil
+}
+
+// noMessagesSince returns whether the ticket's last message is at or before lastMessageId, in which case an empty
+// replay is complete, even though the history buffer does not reach back that far.
+func (c *Client) noMessagesSince(ctx context.Context, lastMessageId uint64) (bool, error) {
+	lastMessage, err := dbclient.Client.TicketLastMessage.Get(ctx, c.GuildId, c.TicketId)
+	if err != nil {
+		return false, err
+	}
+
+	return lastMessage.LastMessageId != nil && *lastMessage.LastMessageId <= lastMessageId, nil
+}
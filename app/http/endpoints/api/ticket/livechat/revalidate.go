@@ -0,0 +1,77 @@
+package livechat
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/TicketsBot-cloud/dashboard/internal/api"
+	"github.com/TicketsBot-cloud/dashboard/log"
+	"go.uber.org/zap"
+)
+
+// Permissions are checked on connect, and then periodically, as the socket may stay open for hours after a staff
+// member is removed from a team or the guild's premium lapses.
+const revalidateInterval = 5 * time.Minute
+
+// startRevalidation must only be called once the client has authenticated
+func (c *Client) startRevalidation() {
+	go func() {
+		ticker := time.NewTicker(revalidateInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-c.revalidate:
+			case <-c.done:
+				return
+			}
+
+			c.revalidatePermissions()
+		}
+	}()
+}
+
+// RequestRevalidation schedules an immediate permission check if the client belongs to the user, or to any user if
+// userId is nil. Never blocks.
+func (c *Client) RequestRevalidation(userId *uint64) {
+	c.mu.Lock()
+	matches := c.Authenticated && (userId == nil || *userId == c.UserId)
+	c.mu.Unlock()
+
+	if !matches {
+		return
+	}
+
+	select {
+	case c.revalidate <- struct{}{}:
+	default: // A check is already pending
+	}
+}
+
+func (c *Client) revalidatePermissions() {
+	var err error
+	if c.IsGuildScoped() {
+		err = c.verifyGuildAccess(c.UserId)
+	} else {
+		_, err = c.verifyTicketAccess(c.UserId)
+	}
+
+	if err == nil {
+		return
+	}
+
+	// Don't disconnect the client because of a transient failure, we will try again on the next tick
+	var requestErr *api.RequestError
+	if !errors.As(err, &requestErr) || requestErr.StatusCode >= http.StatusInternalServerError {
+		log.Logger.Warn("Failed to revalidate live-chat permissions", zap.Error(err), zap.Uint64("guild_id", c.GuildId), zap.Int("ticket_id", c.TicketId))
+		return
+	}
+
+	permissionRevocations.Inc()
+
+	c.writeEvent(EventTypeUnauthorized, NewErrorMessage(requestErr.Error()))
+	c.Flush()
+	c.Disconnect()
+}
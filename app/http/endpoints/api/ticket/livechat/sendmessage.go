@@ -0,0 +1,120 @@
+package livechat
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/TicketsBot-cloud/common/permission"
+	"github.com/TicketsBot-cloud/dashboard/app"
+	"github.com/TicketsBot-cloud/dashboard/app/http/middleware"
+	"github.com/TicketsBot-cloud/dashboard/botcontext"
+	dbclient "github.com/TicketsBot-cloud/dashboard/database"
+	"github.com/TicketsBot-cloud/dashboard/log"
+	"github.com/TicketsBot-cloud/dashboard/redis"
+	"github.com/TicketsBot-cloud/dashboard/utils"
+	"github.com/go-redis/redis_rate/v9"
+	"go.uber.org/zap"
+)
+
+// Shares the bucket of the limit applied to POST /api/:id/tickets/:ticketId, so that sending over live-chat does not
+// raise the guild's overall send rate
+var sendMessageLimit = redis_rate.Limit{
+	Rate:   5,
+	Burst:  5,
+	Period: time.Second * 5,
+}
+
+const sendMessagePath = "/api/:id/tickets/:ticketId"
+
+func (c *Client) handleSendMessageEvent(data SendMessageData) {
+	if len(data.Content) == 0 {
+		c.writeNonceError(data.Nonce, "You must enter a message")
+		return
+	}
+
+	ctx, cancel := app.DefaultContext()
+	defer cancel()
+
+	// The ticket opener can view the ticket, but sending goes through the staff webhook, so require the same
+	// permission level as POST /api/:id/tickets/:ticketId
+	permLevel, err := utils.GetPermissionLevel(ctx, c.GuildId, c.UserId)
+	if err != nil {
+		c.writeNonceError(data.Nonce, "Error retrieving permission data")
+		return
+	}
+
+	if permLevel < permission.Support {
+		c.writeNonceError(data.Nonce, "You do not have permission to send messages from the dashboard")
+		return
+	}
+
+	allowed, err := takeSendMessageToken(ctx, c.GuildId)
+	if err != nil {
+		c.writeNonceError(data.Nonce, "An internal server error occurred")
+		return
+	}
+
+	if !allowed {
+		c.writeNonceError(data.Nonce, "You are being ratelimited")
+		return
+	}
+
+	// Premium and ticket permissions are verified when the client authenticates
+	ticket, err := dbclient.Client.Tickets.Get(ctx, c.TicketId, c.GuildId)
+	if err != nil {
+		c.writeNonceError(data.Nonce, "Error retrieving ticket data")
+		return
+	}
+
+	if ticket.UserId == 0 || ticket.GuildId != c.GuildId {
+		c.writeNonceError(data.Nonce, "Ticket not found")
+		return
+	}
+
+	botContext, err := botcontext.ContextForGuild(c.GuildId)
+	if err != nil {
+		c.writeNonceError(data.Nonce, "Error retrieving bot context")
+		return
+	}
+
+	if err := utils.SendDashboardMessage(ctx, botContext, ticket, c.UserId, utils.DashboardMessage{Content: data.Content}); err != nil {
+		log.Logger.Warn("Failed to send live-chat message", zap.Error(err), zap.Uint64("guild_id", c.GuildId), zap.Int("ticket_id", c.TicketId))
+		c.writeNonceError(data.Nonce, err.Error())
+		return
+	}
+
+	c.writeEvent(EventTypeAck, AckData{
+		Nonce: data.Nonce,
+	})
+}
+
+func (c *Client) writeNonceError(nonce, message string) {
+	c.writeEvent(EventTypeError, NonceErrorData{
+		Nonce: nonce,
+		Error: message,
+	})
+}
+
+func (c *Client) writeEvent(eventType EventType, data any) {
+	event, err := NewEvent(eventType, data)
+	if err != nil {
+		c.Write(NewErrorMessage(err.Error()))
+		return
+	}
+
+	c.Write(event)
+}
+
+func takeSendMessageToken(ctx context.Context, guildId uint64) (bool, error) {
+	limiter := redis_rate.NewLimiter(redis.Client)
+
+	key := middleware.RateLimitKey(middleware.RateLimitTypeGuild, strconv.FormatUint(guildId, 10), sendMessageLimit, sendMessagePath)
+
+	res, err := limiter.Allow(ctx, key, sendMessageLimit)
+	if err != nil {
+		return false, err
+	}
+
+	return res.Allowed > 0, nil
+}
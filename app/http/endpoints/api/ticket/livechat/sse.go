@@ -0,0 +1,130 @@
+package livechat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/TicketsBot-cloud/dashboard/internal/api"
+	"github.com/TicketsBot-cloud/dashboard/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// GetLiveChatSSEHandler streams the same events as the live-chat websocket over Server-Sent Events, for clients whose
+// network does not allow websocket upgrades. Unlike the websocket, authentication uses the Authorization header, and
+// the stream is read-only: messages must be sent through the regular HTTP endpoint.
+func GetLiveChatSSEHandler(sm *SocketManager) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		guildId := ctx.Keys["guildid"].(uint64)
+		userId := ctx.Keys["userid"].(uint64)
+
+		ticketId, err := strconv.Atoi(ctx.Param("ticketId"))
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, utils.ErrorStr("Invalid ticket ID"))
+			return
+		}
+
+		// EventSource sends the ID of the last event it received when reconnecting
+		var lastMessageId *uint64
+		if raw := ctx.GetHeader("Last-Event-ID"); raw != "" {
+			parsed, err := strconv.ParseUint(raw, 10, 64)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, utils.ErrorStr("Invalid Last-Event-ID"))
+				return
+			}
+
+			lastMessageId = &parsed
+		}
+
+		client := NewClient(sm, nil, ctx, guildId, ticketId)
+
+		// Register before authenticating, so that no messages are missed between the replay and live delivery
+		sm.register <- client
+		defer func() {
+			sm.unregister <- client
+			client.Close()
+		}()
+
+		if err := client.authenticate(userId, lastMessageId); err != nil {
+			var requestErr *api.RequestError
+			if errors.As(err, &requestErr) {
+				ctx.JSON(requestErr.StatusCode, utils.ErrorJson(requestErr))
+			} else {
+				ctx.JSON(http.StatusInternalServerError, utils.ErrorJson(err))
+			}
+
+			return
+		}
+
+		ctx.Header("Content-Type", "text/event-stream")
+		ctx.Header("Cache-Control", "no-cache")
+		ctx.Header("Connection", "keep-alive")
+		ctx.Header("X-Accel-Buffering", "no")
+		ctx.Status(http.StatusOK)
+
+		if err := client.StartSSEWriteLoop(); err != nil {
+			_ = ctx.Error(err)
+		}
+	}
+}
+
+// StartSSEWriteLoop drains the client's outbound queue into the response until the client disconnects or is closed.
+func (c *Client) StartSSEWriteLoop() error {
+	ticker := time.NewTicker(keepaliveFrequency)
+	defer ticker.Stop()
+
+	w := c.RequestCtx.Writer
+	w.Flush()
+
+	for {
+		select {
+		case message := <-c.tx:
+			if flush, ok := message.(flushRequest); ok {
+				close(flush)
+				continue
+			}
+
+			if err := writeSSEEvent(w, message); err != nil {
+				return err
+			}
+
+			w.Flush()
+		case <-ticker.C:
+			// Comments are ignored by EventSource, but stop proxies from closing an idle connection
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return err
+			}
+
+			w.Flush()
+		case <-c.RequestCtx.Request.Context().Done():
+			return nil
+		case <-c.done:
+			return nil
+		}
+	}
+}
+
+func writeSSEEvent(w gin.ResponseWriter, message any) error {
+	encoded, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	if event, ok := message.(Event); ok {
+		if event.messageId != 0 {
+			if _, err := fmt.Fprintf(w, "id: %d\n", event.messageId); err != nil {
+				return err
+			}
+		}
+
+		if _, err := fmt.Fprintf(w, "event: %s\n", event.Type); err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(w, "data: %s\n\n", encoded)
+	return err
+}
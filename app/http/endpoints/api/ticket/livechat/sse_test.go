@@ -0,0 +1,32 @@
+package livechat
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSSEEvent(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+
+	event := Event{
+		Type:      EventTypeMessage,
+		Data:      []byte(`{"id":"123"}`),
+		messageId: 123,
+	}
+
+	require.NoError(t, writeSSEEvent(ctx.Writer, event))
+	assert.Equal(t, "id: 123\nevent: message\ndata: {\"type\":\"message\",\"data\":{\"id\":\"123\"}}\n\n", recorder.Body.String())
+}
+
+func TestWriteSSEEventWithoutId(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+
+	require.NoError(t, writeSSEEvent(ctx.Writer, Event{Type: EventTypeAuthenticated}))
+	assert.Equal(t, "event: authenticated\ndata: {\"type\":\"authenticated\"}\n\n", recorder.Body.String())
+}
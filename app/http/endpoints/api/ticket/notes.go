@@ -0,0 +1,242 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TicketsBot-cloud/common/permission"
+	"github.com/TicketsBot-cloud/dashboard/app"
+	dbclient "github.com/TicketsBot-cloud/dashboard/database"
+	"github.com/TicketsBot-cloud/dashboard/log"
+	"github.com/TicketsBot-cloud/dashboard/redis"
+	"github.com/TicketsBot-cloud/dashboard/utils"
+	"github.com/TicketsBot-cloud/database"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type noteBody struct {
+	Content string `json:"content"`
+}
+
+const maxNoteLength = 4000
+
+func ListNotes(ctx *gin.Context) {
+	guildId := ctx.Keys["guildid"].(uint64)
+	userId := ctx.Keys["userid"].(uint64)
+
+	ticket, ok := getNoteTicket(ctx, guildId, userId)
+	if !ok {
+		return
+	}
+
+	notes, err := dbclient.Dashboard.TicketNotes.GetByTicket(ctx, guildId, ticket.Id)
+	if err != nil {
+		_ = ctx.AbortWithError(http.StatusInternalServerError, app.NewServerError(err))
+		return
+	}
+
+	ctx.JSON(200, notes)
+}
+
+func CreateNote(ctx *gin.Context) {
+	guildId := ctx.Keys["guildid"].(uint64)
+	userId := ctx.Keys["userid"].(uint64)
+
+	content, ok := parseNoteBody(ctx)
+	if !ok {
+		return
+	}
+
+	ticket, ok := getNoteTicket(ctx, guildId, userId)
+	if !ok {
+		return
+	}
+
+	note := dbclient.TicketNote{
+		GuildId:   guildId,
+		TicketId:  ticket.Id,
+		AuthorId:  userId,
+		Content:   content,
+		CreatedAt: time.Now(),
+	}
+
+	id, err := dbclient.Dashboard.TicketNotes.Create(ctx, note)
+	if err != nil {
+		_ = ctx.AbortWithError(http.StatusInternalServerError, app.NewServerError(err))
+		return
+	}
+
+	note.Id = id
+
+	publishNoteEvent(ctx, redis.TicketNoteCreated, note)
+	ctx.JSON(200, note)
+}
+
+// UpdateNote edits a note's content. Only the author of a note, or an admin, can edit or delete it.
+func UpdateNote(ctx *gin.Context) {
+	guildId := ctx.Keys["guildid"].(uint64)
+	userId := ctx.Keys["userid"].(uint64)
+
+	content, ok := parseNoteBody(ctx)
+	if !ok {
+		return
+	}
+
+	note, ok := getEditableNote(ctx, guildId, userId)
+	if !ok {
+		return
+	}
+
+	editedAt := time.Now()
+	if err := dbclient.Dashboard.TicketNotes.Update(ctx, guildId, note.TicketId, note.Id, content, editedAt); err != nil {
+		_ = ctx.AbortWithError(http.StatusInternalServerError, app.NewServerError(err))
+		return
+	}
+
+	note.Content = content
+	note.EditedAt = &editedAt
+
+	publishNoteEvent(ctx, redis.TicketNoteUpdated, note)
+	ctx.JSON(200, note)
+}
+
+func DeleteNote(ctx *gin.Context) {
+	guildId := ctx.Keys["guildid"].(uint64)
+	userId := ctx.Keys["userid"].(uint64)
+
+	note, ok := getEditableNote(ctx, guildId, userId)
+	if !ok {
+		return
+	}
+
+	if err := dbclient.Dashboard.TicketNotes.Delete(ctx, guildId, note.TicketId, note.Id); err != nil {
+		_ = ctx.AbortWithError(http.StatusInternalServerError, app.NewServerError(err))
+		return
+	}
+
+	publishNoteEvent(ctx, redis.TicketNoteDeleted, dbclient.TicketNote{
+		Id:       note.Id,
+		GuildId:  note.GuildId,
+		TicketId: note.TicketId,
+	})
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func parseNoteBody(ctx *gin.Context) (string, bool) {
+	var body noteBody
+	if err := ctx.BindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorStr("Invalid request body"))
+		return "", false
+	}
+
+	content := strings.TrimSpace(body.Content)
+	if len(content) == 0 {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorStr("Notes cannot be empty"))
+		return "", false
+	}
+
+	if len(content) > maxNoteLength {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorStr("Notes must be 4000 characters or fewer"))
+		return "", false
+	}
+
+	return content, true
+}
+
+// getNoteTicket fetches the ticket, verifying that the user has permission to view it. Notes can be left on closed
+// tickets too, for example to record the outcome after closing. If ok is false, a response has already been written.
+func getNoteTicket(ctx *gin.Context, guildId, userId uint64) (database.Ticket, bool) {
+	ticketId, err := strconv.Atoi(ctx.Param("ticketId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorStr("Invalid ticket ID"))
+		return database.Ticket{}, false
+	}
+
+	ticket, err := dbclient.Client.Tickets.Get(ctx, ticketId, guildId)
+	if err != nil {
+		_ = ctx.AbortWithError(http.StatusInternalServerError, app.NewServerError(err))
+		return database.Ticket{}, false
+	}
+
+	if ticket.UserId == 0 {
+		ctx.JSON(http.StatusNotFound, utils.ErrorStr("Ticket not found"))
+		return database.Ticket{}, false
+	}
+
+	hasPermission, requestErr := utils.HasPermissionToViewTicket(ctx, guildId, userId, ticket)
+	if requestErr != nil {
+		ctx.JSON(requestErr.StatusCode, utils.ErrorJson(requestErr))
+		return database.Ticket{}, false
+	}
+
+	if !hasPermission {
+		ctx.JSON(http.StatusForbidden, utils.ErrorStr("You do not have permission to view this ticket"))
+		return database.Ticket{}, false
+	}
+
+	return ticket, true
+}
+
+// getEditableNote fetches the note, verifying that the user is its author or an admin. If ok is false, a response has
+// already been written.
+func getEditableNote(ctx *gin.Context, guildId, userId uint64) (dbclient.TicketNote, bool) {
+	ticket, ok := getNoteTicket(ctx, guildId, userId)
+	if !ok {
+		return dbclient.TicketNote{}, false
+	}
+
+	noteId, err := strconv.Atoi(ctx.Param("noteId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorStr("Invalid note ID"))
+		return dbclient.TicketNote{}, false
+	}
+
+	note, ok, err := dbclient.Dashboard.TicketNotes.Get(ctx, guildId, ticket.Id, noteId)
+	if err != nil {
+		_ = ctx.AbortWithError(http.StatusInternalServerError, app.NewServerError(err))
+		return dbclient.TicketNote{}, false
+	}
+
+	if !ok {
+		ctx.JSON(http.StatusNotFound, utils.ErrorStr("Note not found"))
+		return dbclient.TicketNote{}, false
+	}
+
+	if note.AuthorId != userId {
+		permLevel, err := utils.GetPermissionLevel(ctx, guildId, userId)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, utils.ErrorJson(err))
+			return dbclient.TicketNote{}, false
+		}
+
+		if permLevel < permission.Admin {
+			ctx.JSON(http.StatusForbidden, utils.ErrorStr("Only the author of a note or an admin can change it"))
+			return dbclient.TicketNote{}, false
+		}
+	}
+
+	return note, true
+}
+
+// publishNoteEvent relays the change to staff viewing the ticket over live chat. The change has already been saved,
+// so a failure to publish is logged rather than returned to the user.
+func publishNoteEvent(ctx context.Context, eventType redis.TicketNoteEventType, note dbclient.TicketNote) {
+	event := redis.TicketNoteEvent{
+		Type: eventType,
+		Note: note,
+	}
+
+	if err := redis.Client.PublishTicketNoteEvent(ctx, event); err != nil {
+		log.Logger.Warn(
+			"Failed to publish ticket note event",
+			zap.Error(err),
+			zap.Uint64("guild_id", note.GuildId),
+			zap.Int("ticket_id", note.TicketId),
+		)
+	}
+}
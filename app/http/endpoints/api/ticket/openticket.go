@@ -0,0 +1,230 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/TicketsBot-cloud/common/permission"
+	"github.com/TicketsBot-cloud/dashboard/app"
+	"github.com/TicketsBot-cloud/dashboard/botcontext"
+	"github.com/TicketsBot-cloud/dashboard/config"
+	dbclient "github.com/TicketsBot-cloud/dashboard/database"
+	"github.com/TicketsBot-cloud/dashboard/redis"
+	"github.com/TicketsBot-cloud/dashboard/rpc"
+	"github.com/TicketsBot-cloud/dashboard/utils"
+	"github.com/TicketsBot-cloud/database"
+	"github.com/gin-gonic/gin"
+	"github.com/rxdn/gdl/objects/member"
+)
+
+type openTicketBody struct {
+	PanelId  int               `json:"panel_id"`
+	UserId   uint64            `json:"user_id,string"`
+	FormData map[string]string `json:"form_data"`
+}
+
+// Staff are exempt from the guild's ticket limit, matching the worker
+const staffTicketLimit = 50
+
+const maxFormAnswerLength = 4000
+
+// OpenTicket opens a ticket on behalf of a member, for example to follow up on a bug they reported. The ticket is
+// created by the worker, so this only queues the request once the same checks as opening from Discord have passed.
+func OpenTicket(ctx *gin.Context) {
+	guildId := ctx.Keys["guildid"].(uint64)
+	userId := ctx.Keys["userid"].(uint64)
+
+	// Otherwise, the request would be queued, and the guild's rate limit token taken, without a ticket ever being opened
+	if !config.Conf.Bot.TicketOpenRelay {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorStr("Opening tickets from the dashboard is not available"))
+		return
+	}
+
+	var body openTicketBody
+	if err := ctx.BindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorStr("Invalid request body"))
+		return
+	}
+
+	panel, err := dbclient.Client.Panel.GetById(ctx, body.PanelId)
+	if err != nil {
+		_ = ctx.AbortWithError(http.StatusInternalServerError, app.NewServerError(err))
+		return
+	}
+
+	if panel.PanelId == 0 || panel.GuildId != guildId {
+		ctx.JSON(http.StatusNotFound, utils.ErrorStr("Panel not found"))
+		return
+	}
+
+	if panel.ForceDisabled || panel.Disabled {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorStr("This panel is disabled"))
+		return
+	}
+
+	errMsg, err := validateFormData(ctx, panel, body.FormData)
+	if err != nil {
+		_ = ctx.AbortWithError(http.StatusInternalServerError, app.NewServerError(err))
+		return
+	}
+
+	if errMsg != "" {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorStr(errMsg))
+		return
+	}
+
+	botContext, err := botcontext.ContextForGuild(guildId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorJson(err))
+		return
+	}
+
+	target, err := botContext.GetGuildMember(ctx, guildId, body.UserId)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorStr("User not found in server"))
+		return
+	}
+
+	if target.User.Bot {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorStr("Tickets cannot be opened for bots"))
+		return
+	}
+
+	targetPermLevel, err := utils.GetPermissionLevel(ctx, guildId, body.UserId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorJson(err))
+		return
+	}
+
+	blacklisted, err := isBlacklisted(ctx, guildId, target, targetPermLevel)
+	if err != nil {
+		_ = ctx.AbortWithError(http.StatusInternalServerError, app.NewServerError(err))
+		return
+	}
+
+	if blacklisted {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorStr("This user is blacklisted"))
+		return
+	}
+
+	limit, err := getTicketLimit(ctx, guildId, targetPermLevel)
+	if err != nil {
+		_ = ctx.AbortWithError(http.StatusInternalServerError, app.NewServerError(err))
+		return
+	}
+
+	openTickets, err := dbclient.Client.Tickets.GetOpenByUser(ctx, guildId, body.UserId)
+	if err != nil {
+		_ = ctx.AbortWithError(http.StatusInternalServerError, app.NewServerError(err))
+		return
+	}
+
+	if len(openTickets) >= limit {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorStr(fmt.Sprintf("This user already has the maximum of %d open tickets", limit)))
+		return
+	}
+
+	// Take the token last, so that a rejected request does not use up the guild's rate limit
+	ok, err := rpc.TakeTicketRateLimitToken(ctx, redis.Client.Client, guildId)
+	if err != nil {
+		_ = ctx.AbortWithError(http.StatusInternalServerError, app.NewServerError(err))
+		return
+	}
+
+	if !ok {
+		ctx.JSON(http.StatusTooManyRequests, utils.ErrorStr("Too many tickets are being opened in this server, please try again shortly"))
+		return
+	}
+
+	request := redis.TicketOpenRequest{
+		GuildId:  guildId,
+		PanelId:  panel.PanelId,
+		UserId:   body.UserId,
+		OpenedBy: userId,
+		FormData: body.FormData,
+	}
+
+	if err := redis.Client.PublishTicketOpen(ctx, request); err != nil {
+		_ = ctx.AbortWithError(http.StatusInternalServerError, app.NewServerError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, utils.SuccessResponse)
+}
+
+// validateFormData checks that each answer belongs to an input on the panel's form. Answers are optional, as staff may
+// not know them all in advance. Returns a message for the user, or an empty string if the answers are valid.
+func validateFormData(ctx context.Context, panel database.Panel, formData map[string]string) (string, error) {
+	if len(formData) == 0 {
+		return "", nil
+	}
+
+	if panel.FormId == nil {
+		return "This panel does not have a form", nil
+	}
+
+	inputs, err := dbclient.Client.FormInput.GetInputs(ctx, *panel.FormId)
+	if err != nil {
+		return "", err
+	}
+
+	inputsByCustomId := make(map[string]database.FormInput, len(inputs))
+	for _, input := range inputs {
+		inputsByCustomId[input.CustomId] = input
+	}
+
+	for customId, answer := range formData {
+		input, ok := inputsByCustomId[customId]
+		if !ok {
+			return "Form answer does not match any of the panel's form inputs", nil
+		}
+
+		maxLength := maxFormAnswerLength
+		if input.MaxLength != nil {
+			maxLength = int(*input.MaxLength)
+		}
+
+		if len(answer) > maxLength {
+			return fmt.Sprintf("Answer to \"%s\" must be %d characters or fewer", input.Label, maxLength), nil
+		}
+	}
+
+	return "", nil
+}
+
+// isBlacklisted mirrors the worker's check: staff bypass the server's user and role blacklists, but not the global one.
+func isBlacklisted(ctx context.Context, guildId uint64, target member.Member, permLevel permission.PermissionLevel) (bool, error) {
+	globalBlacklisted, err := dbclient.Client.GlobalBlacklist.IsBlacklisted(ctx, target.User.Id)
+	if err != nil {
+		return false, err
+	}
+
+	if globalBlacklisted {
+		return true, nil
+	}
+
+	if permLevel >= permission.Support {
+		return false, nil
+	}
+
+	userBlacklisted, err := dbclient.Client.Blacklist.IsBlacklisted(ctx, guildId, target.User.Id)
+	if err != nil {
+		return false, err
+	}
+
+	if userBlacklisted {
+		return true, nil
+	}
+
+	return dbclient.Client.RoleBlacklist.IsAnyBlacklisted(ctx, guildId, target.Roles)
+}
+
+func getTicketLimit(ctx context.Context, guildId uint64, permLevel permission.PermissionLevel) (int, error) {
+	if permLevel >= permission.Support {
+		return staffTicketLimit, nil
+	}
+
+	limit, err := dbclient.Client.TicketLimit.Get(ctx, guildId)
+	return int(limit), err
+}
@@ -0,0 +1,240 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/TicketsBot-cloud/common/permission"
+	"github.com/TicketsBot-cloud/dashboard/app"
+	"github.com/TicketsBot-cloud/dashboard/botcontext"
+	dbclient "github.com/TicketsBot-cloud/dashboard/database"
+	"github.com/TicketsBot-cloud/dashboard/utils"
+	"github.com/TicketsBot-cloud/database"
+	"github.com/gin-gonic/gin"
+	"github.com/rxdn/gdl/objects/channel"
+	"github.com/rxdn/gdl/rest/request"
+)
+
+type participantType int
+
+const (
+	participantTypeUser participantType = iota
+	participantTypeRole
+)
+
+var participantTypes = map[int]participantType{
+	int(participantTypeUser): participantTypeUser,
+	int(participantTypeRole): participantTypeRole,
+}
+
+func (t participantType) overwriteType() channel.PermissionOverwriteType {
+	if t == participantTypeRole {
+		return channel.PermissionTypeRole
+	}
+
+	return channel.PermissionTypeMember
+}
+
+func (t participantType) mention(id uint64) string {
+	if t == participantTypeRole {
+		return fmt.Sprintf("<@&%d>", id)
+	}
+
+	return fmt.Sprintf("<@%d>", id)
+}
+
+// AddParticipant adds a user or role to the ticket, e.g. to bring in a colleague from another team. Pass ?type=0 for
+// a user and ?type=1 for a role.
+func AddParticipant(ctx *gin.Context) {
+	guildId := ctx.Keys["guildid"].(uint64)
+	userId := ctx.Keys["userid"].(uint64)
+
+	ticket, snowflake, entityType, botContext, ok := getParticipantRequest(ctx, guildId, userId)
+	if !ok {
+		return
+	}
+
+	if entityType == participantTypeUser && snowflake == ticket.UserId {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorStr("This user opened the ticket"))
+		return
+	}
+
+	if err := utils.AddTicketParticipant(ctx, botContext, ticket, snowflake, entityType.overwriteType()); err != nil {
+		var restError request.RestError
+		if errors.As(err, &restError) && restError.StatusCode == http.StatusForbidden && ticket.IsThread {
+			ctx.JSON(http.StatusBadRequest, utils.ErrorStr("The user must be able to view the ticket's parent channel to be added to a thread"))
+		} else {
+			_ = ctx.AbortWithError(http.StatusInternalServerError, app.NewServerError(err))
+		}
+
+		return
+	}
+
+	notifyTicket(ctx, botContext, ticket, "Participant Added", fmt.Sprintf("%s has been added to this ticket by <@%d>", entityType.mention(snowflake), userId))
+	ctx.Status(http.StatusNoContent)
+}
+
+// RemoveParticipant removes a user or role from the ticket. Staff cannot be removed, as they would regain access to
+// the ticket through their support team anyway.
+func RemoveParticipant(ctx *gin.Context) {
+	guildId := ctx.Keys["guildid"].(uint64)
+	userId := ctx.Keys["userid"].(uint64)
+
+	ticket, snowflake, entityType, botContext, ok := getParticipantRequest(ctx, guildId, userId)
+	if !ok {
+		return
+	}
+
+	switch entityType {
+	case participantTypeUser:
+		if snowflake == ticket.UserId {
+			ctx.JSON(http.StatusBadRequest, utils.ErrorStr("You cannot remove the user who opened the ticket"))
+			return
+		}
+
+		member, err := botContext.GetGuildMember(ctx, guildId, snowflake)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, utils.ErrorStr("User not found in server"))
+			return
+		}
+
+		permLevel, err := permission.GetPermissionLevel(ctx, botContext, member, guildId)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, utils.ErrorJson(err))
+			return
+		}
+
+		if permLevel > permission.Everyone {
+			ctx.JSON(http.StatusBadRequest, utils.ErrorStr("You cannot remove staff members from a ticket"))
+			return
+		}
+	case participantTypeRole:
+		isStaff, err := isStaffRole(ctx, guildId, snowflake)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, utils.ErrorJson(err))
+			return
+		}
+
+		if isStaff {
+			ctx.JSON(http.StatusBadRequest, utils.ErrorStr("You cannot remove staff roles from a ticket"))
+			return
+		}
+	}
+
+	if err := utils.RemoveTicketParticipant(ctx, botContext, ticket, snowflake, entityType.overwriteType()); err != nil {
+		_ = ctx.AbortWithError(http.StatusInternalServerError, app.NewServerError(err))
+		return
+	}
+
+	notifyTicket(ctx, botContext, ticket, "Participant Removed", fmt.Sprintf("%s has been removed from this ticket by <@%d>", entityType.mention(snowflake), userId))
+	ctx.Status(http.StatusNoContent)
+}
+
+// getParticipantRequest parses and validates the ticket and the user or role being added or removed, verifying that
+// the entity belongs to the guild. If ok is false, a response has already been written.
+func getParticipantRequest(ctx *gin.Context, guildId, userId uint64) (
+	ticket database.Ticket,
+	snowflake uint64,
+	entityType participantType,
+	botContext *botcontext.BotContext,
+	ok bool,
+) {
+	ticketId, err := strconv.Atoi(ctx.Param("ticketId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorStr("Invalid ticket ID"))
+		return
+	}
+
+	snowflake, err = strconv.ParseUint(ctx.Param("snowflake"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorStr("Invalid user or role ID"))
+		return
+	}
+
+	typeParsed, err := strconv.Atoi(ctx.Query("type"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorStr("Invalid entity type"))
+		return
+	}
+
+	entityType, valid := participantTypes[typeParsed]
+	if !valid {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorStr("Invalid entity type"))
+		return
+	}
+
+	if entityType == participantTypeRole && snowflake == guildId {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorStr("You cannot add or remove the @everyone role"))
+		return
+	}
+
+	ticket, err = dbclient.Client.Tickets.Get(ctx, ticketId, guildId)
+	if err != nil {
+		_ = ctx.AbortWithError(http.StatusInternalServerError, app.NewServerError(err))
+		return
+	}
+
+	if ticket.UserId == 0 || !ticket.Open {
+		ctx.JSON(http.StatusNotFound, utils.ErrorStr("Ticket not found"))
+		return
+	}
+
+	if ticket.ChannelId == nil {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorStr("Ticket channel not found"))
+		return
+	}
+
+	if ticket.IsThread && entityType == participantTypeRole {
+		ctx.JSON(http.StatusBadRequest, utils.ErrorStr("Roles cannot be added to or removed from thread tickets"))
+		return
+	}
+
+	hasPermission, requestErr := utils.HasPermissionToViewTicket(ctx, guildId, userId, ticket)
+	if requestErr != nil {
+		ctx.JSON(requestErr.StatusCode, utils.ErrorJson(requestErr))
+		return
+	}
+
+	if !hasPermission {
+		ctx.JSON(http.StatusForbidden, utils.ErrorStr("You do not have permission to manage this ticket"))
+		return
+	}
+
+	botContext, err = botcontext.ContextForGuild(guildId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, utils.ErrorJson(err))
+		return
+	}
+
+	// Verify the user or role belongs to the guild
+	switch entityType {
+	case participantTypeUser:
+		if _, err := botContext.GetGuildMember(ctx, guildId, snowflake); err != nil {
+			ctx.JSON(http.StatusBadRequest, utils.ErrorStr("User not found in server"))
+			return
+		}
+	case participantTypeRole:
+		roles, err := botContext.GetGuildRoles(ctx, guildId)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, utils.ErrorJson(err))
+			return
+		}
+
+		if !utils.ExistsMap(roles, snowflake, utils.RoleToId) {
+			ctx.JSON(http.StatusBadRequest, utils.ErrorStr("Role not found in server"))
+			return
+		}
+	}
+
+	return ticket, snowflake, entityType, botContext, true
+}
+
+func isStaffRole(ctx *gin.Context, guildId, roleId uint64) (bool, error) {
+	if isSupport, err := dbclient.Client.RolePermissions.IsSupport(ctx, roleId); err != nil || isSupport {
+		return isSupport, err
+	}
+
+	return dbclient.Client.SupportTeamRoles.IsSupport(ctx, guildId, roleId)
+}
@@ -1,28 +1,49 @@
 package api
 
 import (
-	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/TicketsBot-cloud/common/premium"
+	"github.com/TicketsBot-cloud/dashboard/app/http/validation"
 	"github.com/TicketsBot-cloud/dashboard/botcontext"
 	"github.com/TicketsBot-cloud/dashboard/database"
 	"github.com/TicketsBot-cloud/dashboard/rpc"
 	"github.com/TicketsBot-cloud/dashboard/utils"
+	"github.com/TicketsBot-cloud/dashboard/utils/types"
 	"github.com/gin-gonic/gin"
-	"github.com/rxdn/gdl/rest"
-	"github.com/rxdn/gdl/rest/request"
+	"github.com/go-playground/validator/v10"
+	"github.com/rxdn/gdl/objects/channel/embed"
 )
 
 type sendMessageBody struct {
 	Message struct {
-		MessageType string `json:"type"`
-		Content     string `json:"content"`
+		MessageType string             `json:"type"`
+		Content     string             `json:"content"`
+		Embed       *types.CustomEmbed `json:"embed" validate:"omitempty"`
 	} `json:"message"`
 }
 
+// Discord allows at most 10 attachments per message
+const maxAttachments = 10
+
+// maxUploadSize is the total size of the files that can be attached to a single reply. Discord applies its own limit
+// based on the guild's boost level, which is reported back to the user if exceeded.
+var maxUploadSize = map[premium.PremiumTier]int64{
+	premium.Premium:    8 * 1024 * 1024,
+	premium.Whitelabel: 25 * 1024 * 1024,
+}
+
+var validate = validator.New()
+
+// SendMessage sends a reply to the ticket. The body is either JSON, or multipart/form-data with the JSON body in the
+// payload_json field and the files to attach in the files field.
 func SendMessage(ctx *gin.Context) {
 	guildId := ctx.Keys["guildid"].(uint64)
 	userId := ctx.Keys["userid"].(uint64)
@@ -46,38 +67,66 @@ func SendMessage(ctx *gin.Context) {
 		return
 	}
 
-	var body sendMessageBody
-	if err := ctx.BindJSON(&body); err != nil {
-		ctx.JSON(400, gin.H{
-			"success": false,
-			"error":   "Message is missing",
-		})
+	// Verify guild is premium
+	premiumTier, err := rpc.PremiumClient.GetTierByGuildId(ctx, guildId, true, botContext.Token, botContext.RateLimiter)
+	if err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
 		return
 	}
 
-	if len(body.Message.Content) == 0 {
-		ctx.JSON(400, gin.H{
+	if premiumTier == premium.None {
+		ctx.JSON(402, gin.H{
 			"success": false,
-			"error":   "You must enter a message",
+			"error":   "Guild is not premium",
 		})
 		return
 	}
 
-	// Verify guild is premium
-	premiumTier, err := rpc.PremiumClient.GetTierByGuildId(ctx, guildId, true, botContext.Token, botContext.RateLimiter)
-	if err != nil {
-		ctx.JSON(500, utils.ErrorJson(err))
+	var body sendMessageBody
+	var attachments []utils.DashboardAttachment
+	if strings.HasPrefix(ctx.ContentType(), "multipart/form-data") {
+		var ok bool
+		body, attachments, ok = parseMultipartMessage(ctx, maxUploadSize[premiumTier])
+		if !ok {
+			return
+		}
+	} else {
+		if err := ctx.BindJSON(&body); err != nil {
+			ctx.JSON(400, gin.H{
+				"success": false,
+				"error":   "Message is missing",
+			})
+			return
+		}
+	}
+
+	if err := validate.Struct(body); err != nil {
+		var validationErrors validator.ValidationErrors
+		if ok := errors.As(err, &validationErrors); !ok {
+			ctx.JSON(500, utils.ErrorStr("An error occurred while validating the message"))
+			return
+		}
+
+		formatted := "Your input contained the following errors:\n" + utils.FormatValidationErrors(validationErrors)
+		ctx.JSON(400, utils.ErrorStr(formatted))
 		return
 	}
 
-	if premiumTier == premium.None {
-		ctx.JSON(402, gin.H{
+	if len(body.Message.Content) == 0 && body.Message.Embed == nil && len(attachments) == 0 {
+		ctx.JSON(400, gin.H{
 			"success": false,
-			"error":   "Guild is not premium",
+			"error":   "You must enter a message",
 		})
 		return
 	}
 
+	if body.Message.Embed != nil {
+		if err := validation.ValidateMessageEmbed(body.Message.Embed); err != nil {
+			ctx.JSON(400, utils.ErrorJson(err))
+			return
+		}
+	}
+
 	// Get ticket
 	ticket, err := database.Client.Tickets.Get(ctx, ticketId, guildId)
 
@@ -99,103 +148,101 @@ func SendMessage(ctx *gin.Context) {
 		return
 	}
 
-	if len(body.Message.Content) > 2000 {
-		body.Message.Content = body.Message.Content[0:1999]
+	msg := utils.DashboardMessage{
+		Content:     body.Message.Content,
+		Attachments: attachments,
 	}
 
-	// Preferably send via a webhook
-	webhook, err := database.Client.Webhooks.Get(ctx, guildId, ticketId)
-	if err != nil {
-		ctx.JSON(500, gin.H{
-			"success": false,
-			"error":   err.Error(),
-		})
-		return
+	if body.Message.Embed != nil {
+		msg.Embeds = []*embed.Embed{body.Message.Embed.IntoDiscordEmbed()}
 	}
 
-	settings, err := database.Client.Settings.Get(ctx, guildId)
-	if err != nil {
-		ctx.JSON(500, utils.ErrorStr("Failed to fetch settings"))
+	if err := utils.SendDashboardMessage(ctx, botContext, ticket, userId, msg); err != nil {
+		ctx.JSON(err.StatusCode, utils.ErrorJson(err))
 		return
 	}
 
-	if webhook.Id != 0 {
-		var webhookData rest.WebhookBody
-		if settings.AnonymiseDashboardResponses {
-			guild, err := botContext.GetGuild(context.Background(), guildId)
-			if err != nil {
-				ctx.JSON(500, utils.ErrorStr("Failed to fetch guild"))
-				return
-			}
-
-			webhookData = rest.WebhookBody{
-				Content:   body.Message.Content,
-				Username:  guild.Name,
-				AvatarUrl: guild.IconUrl(),
-			}
+	ctx.JSON(200, gin.H{
+		"success": true,
+	})
+}
+
+// parseMultipartMessage reads the message body and attached files from a multipart request, rejecting the request if
+// the files exceed maxSize in total. If ok is false, a response has already been written.
+func parseMultipartMessage(ctx *gin.Context, maxSize int64) (body sendMessageBody, attachments []utils.DashboardAttachment, ok bool) {
+	// Leave some headroom for the JSON payload and multipart boundaries
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxSize+1024*1024)
+
+	form, err := ctx.MultipartForm()
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			ctx.JSON(413, utils.ErrorStr(fmt.Sprintf("Attachments must not exceed %dMB in total", maxSize/1024/1024)))
 		} else {
-			user, err := botContext.GetUser(context.Background(), userId)
-			if err != nil {
-				ctx.JSON(500, utils.ErrorStr("Failed to fetch user"))
-				return
-			}
-
-			webhookData = rest.WebhookBody{
-				Content:   body.Message.Content,
-				Username:  user.EffectiveName(),
-				AvatarUrl: user.AvatarUrl(256),
-			}
+			ctx.JSON(400, utils.ErrorStr("Invalid multipart body"))
 		}
 
-		// TODO: Ratelimit
-		_, err = rest.ExecuteWebhook(ctx, webhook.Token, nil, webhook.Id, true, webhookData)
+		return
+	}
 
-		if err != nil {
-			// We can delete the webhook in this case
-			var unwrapped request.RestError
-			if errors.As(err, &unwrapped); unwrapped.StatusCode == 403 || unwrapped.StatusCode == 404 {
-				go database.Client.Webhooks.Delete(ctx, guildId, ticketId)
-			}
-		} else {
-			ctx.JSON(200, gin.H{
-				"success": true,
-			})
+	if payload := form.Value["payload_json"]; len(payload) > 0 {
+		if err := json.Unmarshal([]byte(payload[0]), &body); err != nil {
+			ctx.JSON(400, utils.ErrorStr("Invalid payload_json"))
 			return
 		}
 	}
 
-	message := body.Message.Content
-	if !settings.AnonymiseDashboardResponses {
-		user, err := botContext.GetUser(context.Background(), userId)
+	files := form.File["files"]
+	if len(files) > maxAttachments {
+		ctx.JSON(400, utils.ErrorStr(fmt.Sprintf("You can attach at most %d files", maxAttachments)))
+		return
+	}
+
+	var totalSize int64
+	for _, header := range files {
+		totalSize += header.Size
+	}
+
+	if totalSize > maxSize {
+		ctx.JSON(413, utils.ErrorStr(fmt.Sprintf("Attachments must not exceed %dMB in total", maxSize/1024/1024)))
+		return
+	}
+
+	attachments = make([]utils.DashboardAttachment, 0, len(files))
+	for _, header := range files {
+		attachment, err := readAttachment(header)
 		if err != nil {
-			ctx.JSON(500, utils.ErrorStr("Failed to fetch user"))
+			ctx.JSON(400, utils.ErrorStr("Failed to read attachment"))
 			return
 		}
 
-		message = fmt.Sprintf("**%s**: %s", user.EffectiveName(), message)
+		attachments = append(attachments, attachment)
 	}
 
-	if len(message) > 2000 {
-		message = message[0:1999]
+	return body, attachments, true
+}
+
+func readAttachment(header *multipart.FileHeader) (utils.DashboardAttachment, error) {
+	file, err := header.Open()
+	if err != nil {
+		return utils.DashboardAttachment{}, err
 	}
 
-	if ticket.ChannelId == nil {
-		ctx.JSON(404, gin.H{
-			"success": false,
-			"error":   "Ticket channel ID is nil",
-		})
-		return
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return utils.DashboardAttachment{}, err
 	}
 
-	if _, err = rest.CreateMessage(ctx, botContext.Token, botContext.RateLimiter, *ticket.ChannelId, rest.CreateMessageData{Content: message}); err != nil {
-		ctx.JSON(500, gin.H{
-			"success": false,
-			"error":   err.Error(),
-		})
-		return
+	contentType := header.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
 	}
 
-	ctx.JSON(200, gin.H{
-		"success": true,
-	})
+	return utils.DashboardAttachment{
+		FileName:    header.Filename,
+		ContentType: contentType,
+		Data:        data,
+	}, nil
 }
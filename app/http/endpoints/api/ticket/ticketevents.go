@@ -0,0 +1,36 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/TicketsBot-cloud/dashboard/log"
+	"github.com/TicketsBot-cloud/dashboard/redis"
+	"github.com/TicketsBot-cloud/database"
+	"go.uber.org/zap"
+)
+
+// publishTicketEvent notifies live ticket lists of a change made from the dashboard. The change has already been
+// applied by this point, so a failure to publish is logged rather than returned to the user.
+func publishTicketEvent(ctx context.Context, eventType redis.TicketEventType, ticket database.Ticket, claimedBy *uint64, actorId uint64) {
+	event := redis.TicketEvent{
+		Type:      eventType,
+		GuildId:   ticket.GuildId,
+		TicketId:  ticket.Id,
+		UserId:    ticket.UserId,
+		PanelId:   ticket.PanelId,
+		ClaimedBy: claimedBy,
+		ActorId:   &actorId,
+		Timestamp: time.Now(),
+	}
+
+	if err := redis.Client.PublishTicketEvent(ctx, event); err != nil {
+		log.Logger.Warn(
+			"Failed to publish ticket event",
+			zap.Error(err),
+			zap.String("type", string(eventType)),
+			zap.Uint64("guild_id", ticket.GuildId),
+			zap.Int("ticket_id", ticket.Id),
+		)
+	}
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/TicketsBot-cloud/dashboard/config"
+	dbclient "github.com/TicketsBot-cloud/dashboard/database"
+	"github.com/TicketsBot-cloud/dashboard/log"
+	"github.com/TicketsBot-cloud/dashboard/s3"
+	"github.com/TicketsBot-cloud/dashboard/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	maxArchiveTranscripts = 10000
+	archiveListLimit      = 10
+	archiveTimeout        = 2 * time.Hour
+	archiveDownloadExpiry = time.Hour
+
+	// Archives record their progress regularly, so one that has not in this long was interrupted, e.g. by a restart
+	archiveStaleAfter = 10 * time.Minute
+)
+
+type createArchiveBody struct {
+	UserId       *uint64    `json:"user_id,string"`
+	PanelId      *int       `json:"panel_id"`
+	ClosedAfter  *time.Time `json:"closed_after"`
+	ClosedBefore *time.Time `json:"closed_before"`
+}
+
+type archiveResponse struct {
+	dbclient.TranscriptArchive
+	DownloadUrl *string    `json:"download_url,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+func newArchiveResponse(archive dbclient.TranscriptArchive) archiveResponse {
+	res := newArchiveResponse(archive)
+
+	// Completed archives are not modified again until they expire
+	if archive.Status == dbclient.TranscriptArchiveStatusComplete {
+		res.ExpiresAt = utils.Ptr(archive.UpdatedAt.Add(utils.TranscriptArchiveExpiry))
+	}
+
+	return res
+}
+
+// CreateArchiveHandler starts building a zip file of every transcript matching the filters in the background
+func CreateArchiveHandler(ctx *gin.Context) {
+	guildId := ctx.Keys["guildid"].(uint64)
+	userId := ctx.Keys["userid"].(uint64)
+
+	if config.Conf.S3Import.ArchiveBucket == "" {
+		ctx.JSON(400, utils.ErrorStr("Transcript archives are not available"))
+		return
+	}
+
+	var body createArchiveBody
+	if err := ctx.BindJSON(&body); err != nil {
+		ctx.JSON(400, utils.ErrorJson(err))
+		return
+	}
+
+	if body.ClosedAfter != nil && body.ClosedBefore != nil && !body.ClosedAfter.Before(*body.ClosedBefore) {
+		ctx.JSON(400, utils.ErrorStr("The start of the date range must be before the end"))
+		return
+	}
+
+	active, err := dbclient.Dashboard.TranscriptArchives.HasActive(ctx, guildId, archiveStaleAfter)
+	if err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	if active {
+		ctx.JSON(409, utils.ErrorStr("An archive is already being built, please wait for it to finish"))
+		return
+	}
+
+	archive := dbclient.TranscriptArchive{
+		Id:           uuid.New(),
+		GuildId:      guildId,
+		RequestedBy:  userId,
+		UserId:       body.UserId,
+		PanelId:      body.PanelId,
+		ClosedAfter:  body.ClosedAfter,
+		ClosedBefore: body.ClosedBefore,
+		Status:       dbclient.TranscriptArchiveStatusPending,
+		CreatedAt:    time.Now(),
+	}
+
+	archive.Total, err = dbclient.Dashboard.Transcripts.Count(ctx, archive.QueryOptions())
+	if err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	if archive.Total == 0 {
+		ctx.JSON(400, utils.ErrorStr("No transcripts match the filters"))
+		return
+	}
+
+	if archive.Total > maxArchiveTranscripts {
+		ctx.JSON(400, utils.ErrorStr(fmt.Sprintf("Archives may contain at most %d transcripts, narrow the filters", maxArchiveTranscripts)))
+		return
+	}
+
+	if err := dbclient.Dashboard.TranscriptArchives.Create(ctx, archive); err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	go func() {
+		buildCtx, cancel := context.WithTimeout(context.Background(), archiveTimeout)
+		defer cancel()
+
+		if err := utils.BuildTranscriptArchive(buildCtx, archive); err != nil {
+			log.Logger.Error("Failed to build transcript archive", zap.Error(err), zap.Uint64("guild_id", guildId), zap.String("archive_id", archive.Id.String()))
+		}
+	}()
+
+	ctx.JSON(202, archiveResponse{
+		TranscriptArchive: archive,
+	})
+}
+
+func ListArchivesHandler(ctx *gin.Context) {
+	guildId := ctx.Keys["guildid"].(uint64)
+
+	archives, err := dbclient.Dashboard.TranscriptArchives.GetByGuild(ctx, guildId, archiveListLimit)
+	if err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	res := make([]archiveResponse, len(archives))
+	for i, archive := range archives {
+		res[i] = newArchiveResponse(archive)
+	}
+
+	ctx.JSON(200, res)
+}
+
+// GetArchiveHandler returns the progress of an archive, and a temporary download URL once it is complete
+func GetArchiveHandler(ctx *gin.Context) {
+	guildId := ctx.Keys["guildid"].(uint64)
+
+	archiveId, err := uuid.Parse(ctx.Param("archiveId"))
+	if err != nil {
+		ctx.JSON(400, utils.ErrorStr("Invalid archive ID"))
+		return
+	}
+
+	archive, ok, err := dbclient.Dashboard.TranscriptArchives.Get(ctx, guildId, archiveId)
+	if err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	if !ok {
+		ctx.JSON(404, utils.ErrorStr("Archive not found"))
+		return
+	}
+
+	res := newArchiveResponse(archive)
+
+	if archive.Status == dbclient.TranscriptArchiveStatusComplete && archive.ObjectKey != nil {
+		params := url.Values{}
+		params.Set("response-content-disposition", fmt.Sprintf(`attachment; filename="transcripts-%s.zip"`, archive.Id))
+
+		downloadUrl, err := s3.S3Client.PresignedGetObject(ctx, config.Conf.S3Import.ArchiveBucket, *archive.ObjectKey, archiveDownloadExpiry, params)
+		if err != nil {
+			ctx.JSON(500, utils.ErrorJson(err))
+			return
+		}
+
+		res.DownloadUrl = utils.Ptr(downloadUrl.String())
+	}
+
+	ctx.JSON(200, res)
+}
+
+// withInterruption reports archives that stopped making progress as failed, as they will never complete
+func withInterruption(archive dbclient.TranscriptArchive) dbclient.TranscriptArchive {
+	isActive := archive.Status == dbclient.TranscriptArchiveStatusPending || archive.Status == dbclient.TranscriptArchiveStatusRunning
+	if isActive && time.Since(archive.UpdatedAt) > archiveStaleAfter {
+		archive.Status = dbclient.TranscriptArchiveStatusFailed
+		archive.Error = utils.Ptr("The archive was interrupted, please try again")
+	}
+
+	return archive
+}
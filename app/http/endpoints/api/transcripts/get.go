@@ -3,12 +3,18 @@ package api
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strconv"
 
 	"github.com/TicketsBot-cloud/archiverclient"
+	"github.com/TicketsBot-cloud/common/permission"
+	"github.com/TicketsBot-cloud/dashboard/botcontext"
+	"github.com/TicketsBot-cloud/dashboard/chatreplica"
 	dbclient "github.com/TicketsBot-cloud/dashboard/database"
+	"github.com/TicketsBot-cloud/dashboard/log"
 	"github.com/TicketsBot-cloud/dashboard/utils"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 func GetTranscriptHandler(ctx *gin.Context) {
@@ -22,6 +28,13 @@ func GetTranscriptHandler(ctx *gin.Context) {
 		return
 	}
 
+	// Defaults to the raw transcript from the archiver
+	format := chatreplica.ExportFormat(ctx.Query("format"))
+	if format != "" && !format.IsValid() {
+		ctx.JSON(400, utils.ErrorStr("Invalid format"))
+		return
+	}
+
 	// get ticket object
 	ticket, err := dbclient.Client.Tickets.Get(ctx, ticketId, guildId)
 	if err != nil {
@@ -65,5 +78,81 @@ func GetTranscriptHandler(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(200, messages)
+	if format == "" {
+		ctx.JSON(200, messages)
+		return
+	}
+
+	payload := buildPayload(ctx, guildId, ticketId, messages)
+
+	exported, err := chatreplica.Export(payload, format)
+	if err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	// Staff notes are internal, so are only appended on request, and only for staff
+	if includeNotes, _ := strconv.ParseBool(ctx.Query("include_notes")); includeNotes {
+		permLevel, err := utils.GetPermissionLevel(ctx, guildId, userId)
+		if err != nil {
+			ctx.JSON(500, utils.ErrorJson(err))
+			return
+		}
+
+		if permLevel < permission.Support {
+			ctx.JSON(403, utils.ErrorStr("Only staff can export staff notes"))
+			return
+		}
+
+		notesSection, err := exportStaffNotes(ctx, guildId, ticketId, &payload, format)
+		if err != nil {
+			ctx.JSON(500, utils.ErrorJson(err))
+			return
+		}
+
+		exported = append(exported, notesSection...)
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="ticket-%d.%s"`, ticketId, format.Extension()))
+	ctx.Data(200, format.ContentType(), exported)
+}
+
+func exportStaffNotes(ctx context.Context, guildId uint64, ticketId int, payload *chatreplica.Payload, format chatreplica.ExportFormat) ([]byte, error) {
+	notes, err := dbclient.Dashboard.TicketNotes.GetByTicket(ctx, guildId, ticketId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(notes) == 0 {
+		return nil, nil
+	}
+
+	botContext, err := botcontext.ContextForGuild(guildId)
+	if err != nil {
+		return nil, err
+	}
+
+	staffNotes := make([]chatreplica.StaffNote, len(notes))
+	for i, note := range notes {
+		staffNotes[i] = chatreplica.StaffNote{
+			Author:    note.AuthorId,
+			Content:   note.Content,
+			CreatedAt: note.CreatedAt,
+		}
+
+		// The author may not have sent any messages in the ticket, so may be missing from the transcript
+		if payload.HasUser(note.AuthorId) {
+			continue
+		}
+
+		author, err := botContext.GetUser(ctx, note.AuthorId)
+		if err != nil {
+			log.Logger.Warn("Failed to fetch staff note author", zap.Error(err), zap.Uint64("guild_id", guildId), zap.Int("ticket_id", ticketId))
+			continue
+		}
+
+		payload.AddUser(author.Id, author.Username, author.AvatarUrl(256))
+	}
+
+	return chatreplica.ExportStaffNotes(*payload, staffNotes, format)
 }
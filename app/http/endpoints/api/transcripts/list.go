@@ -3,11 +3,13 @@ package api
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/TicketsBot-cloud/dashboard/botcontext"
 	dbclient "github.com/TicketsBot-cloud/dashboard/database"
 	"github.com/TicketsBot-cloud/dashboard/rpc/cache"
 	"github.com/TicketsBot-cloud/dashboard/utils"
+	"github.com/TicketsBot-cloud/database"
 	"github.com/gin-gonic/gin"
 	cache2 "github.com/rxdn/gdl/cache"
 )
@@ -15,14 +17,25 @@ import (
 const pageLimit = 15
 
 type transcriptMetadata struct {
-	TicketId      int     `json:"ticket_id"`
-	Username      string  `json:"username"`
-	CloseReason   *string `json:"close_reason"`
-	ClosedBy      *uint64 `json:"closed_by"`
-	Rating        *uint8  `json:"rating"`
-	HasTranscript bool    `json:"has_transcript"`
+	TicketId      int        `json:"ticket_id"`
+	Username      string     `json:"username"`
+	CloseReason   *string    `json:"close_reason"`
+	ClosedBy      *uint64    `json:"closed_by"`
+	Rating        *uint8     `json:"rating"`
+	RatingComment *string    `json:"rating_comment"`
+	HasTranscript bool       `json:"has_transcript"`
+	OpenTime      time.Time  `json:"open_time"`
+	CloseTime     *time.Time `json:"close_time"`
+	PanelId       *int       `json:"panel_id"`
 }
 
+type transcriptPage struct {
+	Transcripts []transcriptMetadata `json:"transcripts"`
+	NextCursor  *string              `json:"next_cursor"`
+	Total       *int                 `json:"total,omitempty"`
+}
+
+// ListTranscripts is the legacy, page number based listing, which returns a bare array
 func ListTranscripts(ctx *gin.Context) {
 	guildId := ctx.Keys["guildid"].(uint64)
 
@@ -32,7 +45,7 @@ func ListTranscripts(ctx *gin.Context) {
 		return
 	}
 
-	opts, err := queryOptions.toQueryOptions(guildId)
+	opts, err := queryOptions.toLegacyQueryOptions(guildId)
 	if err != nil {
 		ctx.JSON(500, utils.ErrorJson(err))
 		return
@@ -44,15 +57,82 @@ func ListTranscripts(ctx *gin.Context) {
 		return
 	}
 
-	botContext, err := botcontext.ContextForGuild(guildId)
+	transcripts, err := buildTranscriptMetadata(ctx, guildId, tickets)
+	if err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	ctx.JSON(200, transcripts)
+}
+
+// ListTranscriptsPage lists transcripts using the filters in the query string, paging with an opaque cursor
+func ListTranscriptsPage(ctx *gin.Context) {
+	guildId := ctx.Keys["guildid"].(uint64)
+
+	var queryOptions wrappedQueryOptions
+	if err := ctx.ShouldBindQuery(&queryOptions); err != nil {
+		ctx.JSON(400, utils.ErrorJson(err))
+		return
+	}
+
+	if queryOptions.Page != 0 {
+		ctx.JSON(400, utils.ErrorStr("Use the cursor to page through transcripts"))
+		return
+	}
+
+	opts, err := queryOptions.toQueryOptions(guildId)
+	if err != nil {
+		ctx.JSON(400, utils.ErrorJson(err))
+		return
+	}
+
+	tickets, err := dbclient.Dashboard.Transcripts.Get(ctx, opts)
+	if err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	transcripts, err := buildTranscriptMetadata(ctx, guildId, tickets)
 	if err != nil {
-		ctx.JSON(500, gin.H{
-			"success": false,
-			"error":   err.Error(),
-		})
+		ctx.JSON(500, utils.ErrorJson(err))
 		return
 	}
 
+	res := transcriptPage{
+		Transcripts: transcripts,
+	}
+
+	// A short page means there are no more results
+	if len(tickets) == opts.Limit {
+		cursor, err := encodeCursor(opts.CursorFor(tickets[len(tickets)-1]))
+		if err != nil {
+			ctx.JSON(500, utils.ErrorJson(err))
+			return
+		}
+
+		res.NextCursor = &cursor
+	}
+
+	if queryOptions.IncludeTotal {
+		total, err := dbclient.Dashboard.Transcripts.Count(ctx, opts)
+		if err != nil {
+			ctx.JSON(500, utils.ErrorJson(err))
+			return
+		}
+
+		res.Total = &total
+	}
+
+	ctx.JSON(200, res)
+}
+
+func buildTranscriptMetadata(ctx context.Context, guildId uint64, tickets []database.Ticket) ([]transcriptMetadata, error) {
+	botContext, err := botcontext.ContextForGuild(guildId)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create a mapping user_id -> username so we can skip duplicates
 	usernames := make(map[uint64]string)
 	for _, ticket := range tickets {
@@ -72,8 +152,7 @@ func ListTranscripts(ctx *gin.Context) {
 				usernames[ticket.UserId] = user.Username
 			}
 		} else {
-			ctx.JSON(500, utils.ErrorJson(err))
-			return
+			return nil, err
 		}
 	}
 
@@ -85,15 +164,18 @@ func ListTranscripts(ctx *gin.Context) {
 
 	ratings, err := dbclient.Client.ServiceRatings.GetMulti(ctx, guildId, ticketIds)
 	if err != nil {
-		ctx.JSON(500, utils.ErrorJson(err))
-		return
+		return nil, err
+	}
+
+	ratingComments, err := dbclient.Dashboard.RatingComments.GetMulti(ctx, guildId, ticketIds)
+	if err != nil {
+		return nil, err
 	}
 
 	// Get close reasons
 	closeReasons, err := dbclient.Client.CloseReason.GetMulti(ctx, guildId, ticketIds)
 	if err != nil {
-		ctx.JSON(500, utils.ErrorJson(err))
-		return
+		return nil, err
 	}
 
 	transcripts := make([]transcriptMetadata, len(tickets))
@@ -102,12 +184,19 @@ func ListTranscripts(ctx *gin.Context) {
 			TicketId:      ticket.Id,
 			Username:      usernames[ticket.UserId],
 			HasTranscript: ticket.HasTranscript,
+			OpenTime:      ticket.OpenTime,
+			CloseTime:     ticket.CloseTime,
+			PanelId:       ticket.PanelId,
 		}
 
 		if v, ok := ratings[ticket.Id]; ok {
 			transcript.Rating = &v
 		}
 
+		if v, ok := ratingComments[ticket.Id]; ok {
+			transcript.RatingComment = &v
+		}
+
 		if v, ok := closeReasons[ticket.Id]; ok {
 			transcript.CloseReason = v.Reason
 			transcript.ClosedBy = v.ClosedBy
@@ -116,5 +205,5 @@ func ListTranscripts(ctx *gin.Context) {
 		transcripts[i] = transcript
 	}
 
-	ctx.JSON(200, transcripts)
+	return transcripts, nil
 }
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"strconv"
+	"time"
 
+	"github.com/TicketsBot-cloud/common/model"
 	dbclient "github.com/TicketsBot-cloud/dashboard/database"
 	"github.com/TicketsBot-cloud/dashboard/rpc/cache"
 	"github.com/TicketsBot-cloud/dashboard/utils"
@@ -15,14 +17,40 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
-// ListSelfTranscripts TODO: Give user option to rate ticket
+type selfTicket struct {
+	TicketId      int                `json:"ticket_id"`
+	GuildId       uint64             `json:"guild_id,string"`
+	GuildName     string             `json:"guild_name"`
+	GuildIcon     string             `json:"guild_icon,omitempty"`
+	Open          bool               `json:"open"`
+	Status        model.TicketStatus `json:"status"`
+	OpenTime      time.Time          `json:"open_time"`
+	CloseTime     *time.Time         `json:"close_time"`
+	HasTranscript bool               `json:"has_transcript"`
+
+	// Only populated for closed tickets
+	Rating        *uint8  `json:"rating,omitempty"`
+	RatingComment *string `json:"rating_comment,omitempty"`
+	CanRate       bool    `json:"can_rate"`
+
+	// Live status, only populated for open tickets
+	ClaimedBy        *uint64    `json:"claimed_by,string,omitempty"`
+	LastResponseTime *time.Time `json:"last_response_time,omitempty"`
+	AwaitingResponse bool       `json:"awaiting_response"`
+}
+
+// ListSelfTranscripts lists the tickets the user has opened, across every guild, newest first
 func ListSelfTranscripts(ctx *gin.Context) {
 	userId := ctx.Keys["userid"].(uint64)
 
-	page, err := strconv.Atoi(ctx.Query("page"))
-	if err != nil {
-		ctx.JSON(400, utils.ErrorJson(err))
-		return
+	page := 1
+	if raw := ctx.Query("page"); raw != "" {
+		var err error
+		page, err = strconv.Atoi(raw)
+		if err != nil {
+			ctx.JSON(400, utils.ErrorJson(err))
+			return
+		}
 	}
 
 	var offset int
@@ -30,9 +58,21 @@ func ListSelfTranscripts(ctx *gin.Context) {
 		offset = pageLimit * (page - 1)
 	}
 
+	var open *bool
+	switch ctx.Query("status") {
+	case "", "all":
+	case "open":
+		open = gdlutils.BoolPtr(true)
+	case "closed":
+		open = gdlutils.BoolPtr(false)
+	default:
+		ctx.JSON(400, utils.ErrorStr("Invalid status"))
+		return
+	}
+
 	opts := database.TicketQueryOptions{
 		UserIds: []uint64{userId},
-		Open:    gdlutils.BoolPtr(false),
+		Open:    open,
 		Order:   database.OrderTypeDescending,
 		Limit:   pageLimit,
 		Offset:  offset,
@@ -44,37 +84,36 @@ func ListSelfTranscripts(ctx *gin.Context) {
 		return
 	}
 
-	type TranscriptData struct {
-		TicketId  int    `json:"ticket_id"`
-		GuildId   uint64 `json:"guild_id"`
-		GuildName string `json:"guild_name"`
-	}
-
 	// TODO: Not O(n)
-	data := make([]TranscriptData, len(tickets))
+	data := make([]selfTicket, len(tickets))
 
 	group, _ := errgroup.WithContext(context.Background())
 	for i, ticket := range tickets {
 		group.Go(func() error {
-			var guildName string
-			{
-				guild, err := cache.Instance.GetGuild(context.Background(), ticket.GuildId)
-				if err == nil {
-					guildName = guild.Name
-				} else if errors.Is(err, cache2.ErrNotFound) {
-					guildName = "Unknown server"
-				} else {
-					return err
-				}
+			data[i] = selfTicket{
+				TicketId:      ticket.Id,
+				GuildId:       ticket.GuildId,
+				GuildName:     "Unknown server",
+				Open:          ticket.Open,
+				Status:        ticket.Status,
+				OpenTime:      ticket.OpenTime,
+				CloseTime:     ticket.CloseTime,
+				HasTranscript: ticket.HasTranscript,
 			}
 
-			data[i] = TranscriptData{
-				TicketId:  ticket.Id,
-				GuildId:   ticket.GuildId,
-				GuildName: guildName,
+			guild, err := cache.Instance.GetGuild(context.Background(), ticket.GuildId)
+			if err == nil {
+				data[i].GuildName = guild.Name
+				data[i].GuildIcon = guild.IconUrl()
+			} else if !errors.Is(err, cache2.ErrNotFound) {
+				return err
 			}
 
-			return nil
+			if ticket.Open {
+				return addLiveStatus(&data[i])
+			} else {
+				return addRating(&data[i], ticket)
+			}
 		})
 	}
 
@@ -85,3 +124,49 @@ func ListSelfTranscripts(ctx *gin.Context) {
 
 	ctx.JSON(200, data)
 }
+
+func addRating(data *selfTicket, ticket database.Ticket) error {
+	rating, ok, err := dbclient.Client.ServiceRatings.Get(context.Background(), ticket.GuildId, ticket.Id)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		data.Rating = &rating
+	}
+
+	comment, ok, err := dbclient.Dashboard.RatingComments.Get(context.Background(), ticket.GuildId, ticket.Id)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		data.RatingComment = &comment
+	}
+
+	data.CanRate, err = canRateTicket(context.Background(), ticket)
+	return err
+}
+
+func addLiveStatus(ticket *selfTicket) error {
+	claimedBy, err := dbclient.Client.TicketClaims.Get(context.Background(), ticket.GuildId, ticket.TicketId)
+	if err != nil {
+		return err
+	}
+
+	if claimedBy != 0 {
+		ticket.ClaimedBy = &claimedBy
+	}
+
+	lastMessage, err := dbclient.Client.TicketLastMessage.Get(context.Background(), ticket.GuildId, ticket.TicketId)
+	if err != nil {
+		return err
+	}
+
+	ticket.LastResponseTime = lastMessage.LastMessageTime
+
+	// The user is waiting on staff if they sent the last message, or if nobody has responded yet
+	ticket.AwaitingResponse = lastMessage.UserIsStaff == nil || !*lastMessage.UserIsStaff
+
+	return nil
+}
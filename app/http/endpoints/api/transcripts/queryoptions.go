@@ -2,41 +2,48 @@ package api
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"strings"
+	"time"
 
 	"github.com/TicketsBot-cloud/dashboard/botcontext"
+	dbclient "github.com/TicketsBot-cloud/dashboard/database"
 	"github.com/TicketsBot-cloud/database"
 	"github.com/rxdn/gdl/utils"
 )
 
+const maxPageLimit = 100
+
+// wrappedQueryOptions is bound from the JSON body of the legacy POST endpoint, or the query string of the GET endpoint
 type wrappedQueryOptions struct {
-	Id          int    `json:"id,string"`
-	Username    string `json:"username"`
-	UserId      uint64 `json:"user_id,string"`
-	PanelId     int    `json:"panel_id"`
-	Page        int    `json:"page"`
-	Rating      int    `json:"rating,string"`
-	ClosedById  uint64 `json:"closed_by_id,string"`
-	ClaimedById uint64 `json:"claimed_by_id,string"`
+	Id           int        `json:"id,string" form:"id"`
+	Username     string     `json:"username" form:"username"`
+	UserId       uint64     `json:"user_id,string" form:"user_id"`
+	PanelId      int        `json:"panel_id" form:"panel_id"`
+	PanelIds     []int      `json:"panel_ids" form:"panel_ids"`
+	Page         int        `json:"page" form:"page"`
+	Rating       int        `json:"rating,string" form:"rating"`
+	ClosedById   uint64     `json:"closed_by_id,string" form:"closed_by_id"`
+	ClaimedById  uint64     `json:"claimed_by_id,string" form:"claimed_by_id"`
+	CloseReason  string     `json:"close_reason" form:"close_reason"`
+	OpenedAfter  *time.Time `json:"opened_after" form:"opened_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	OpenedBefore *time.Time `json:"opened_before" form:"opened_before" time_format:"2006-01-02T15:04:05Z07:00"`
+	ClosedAfter  *time.Time `json:"closed_after" form:"closed_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	ClosedBefore *time.Time `json:"closed_before" form:"closed_before" time_format:"2006-01-02T15:04:05Z07:00"`
+	Sort         string     `json:"sort" form:"sort"`
+	Order        string     `json:"order" form:"order"`
+	Cursor       string     `json:"cursor" form:"cursor"`
+	Limit        int        `json:"limit" form:"limit"`
+	IncludeTotal bool       `json:"include_total" form:"include_total"`
 }
 
-func (o *wrappedQueryOptions) toQueryOptions(guildId uint64) (database.TicketQueryOptions, error) {
-	var userIds []uint64
-	if len(o.Username) > 0 {
-		var err error
-		userIds, err = usernameToIds(guildId, o.Username)
-		if err != nil {
-			return database.TicketQueryOptions{}, err
-		}
-
-		// TODO: Do this better
-		if len(userIds) == 0 {
-			return database.TicketQueryOptions{}, errors.New("user not found")
-		}
-	}
-
-	if o.UserId != 0 {
-		userIds = append(userIds, o.UserId)
+// toLegacyQueryOptions builds the options for the legacy endpoint, which only supports the original filters
+func (o *wrappedQueryOptions) toLegacyQueryOptions(guildId uint64) (database.TicketQueryOptions, error) {
+	userIds, err := o.userIds(guildId)
+	if err != nil {
+		return database.TicketQueryOptions{}, err
 	}
 
 	var offset int
@@ -64,6 +71,122 @@ func (o *wrappedQueryOptions) toQueryOptions(guildId uint64) (database.TicketQue
 	return opts, nil
 }
 
+func (o *wrappedQueryOptions) toQueryOptions(guildId uint64) (dbclient.TranscriptQueryOptions, error) {
+	userIds, err := o.userIds(guildId)
+	if err != nil {
+		return dbclient.TranscriptQueryOptions{}, err
+	}
+
+	limit := pageLimit
+	if o.Limit > 0 {
+		limit = min(o.Limit, maxPageLimit)
+	}
+
+	if o.Rating < 0 || o.Rating > 5 {
+		o.Rating = 0
+	}
+
+	panelIds := o.PanelIds
+	if o.PanelId > 0 {
+		panelIds = append(panelIds, o.PanelId)
+	}
+
+	if len(o.CloseReason) > 100 {
+		return dbclient.TranscriptQueryOptions{}, errors.New("close reason filter too long")
+	}
+
+	opts := dbclient.TranscriptQueryOptions{
+		Id:           o.Id,
+		GuildId:      guildId,
+		UserIds:      userIds,
+		PanelIds:     panelIds,
+		Rating:       o.Rating,
+		ClosedById:   o.ClosedById,
+		ClaimedById:  o.ClaimedById,
+		CloseReason:  o.CloseReason,
+		OpenedAfter:  o.OpenedAfter,
+		OpenedBefore: o.OpenedBefore,
+		ClosedAfter:  o.ClosedAfter,
+		ClosedBefore: o.ClosedBefore,
+		Sort:         dbclient.TranscriptSortId,
+		Order:        database.OrderTypeDescending,
+		Limit:        limit,
+	}
+
+	switch o.Sort {
+	case "", string(dbclient.TranscriptSortId):
+	case string(dbclient.TranscriptSortCloseTime):
+		opts.Sort = dbclient.TranscriptSortCloseTime
+	default:
+		return dbclient.TranscriptQueryOptions{}, errors.New("invalid sort")
+	}
+
+	switch strings.ToLower(o.Order) {
+	case "", "desc":
+	case "asc":
+		opts.Order = database.OrderTypeAscending
+	default:
+		return dbclient.TranscriptQueryOptions{}, errors.New("invalid order")
+	}
+
+	if o.Cursor != "" {
+		cursor, err := decodeCursor(o.Cursor)
+		if err != nil {
+			return dbclient.TranscriptQueryOptions{}, errors.New("invalid cursor")
+		}
+
+		opts.After = &cursor
+	}
+
+	return opts, nil
+}
+
+func (o *wrappedQueryOptions) userIds(guildId uint64) ([]uint64, error) {
+	var userIds []uint64
+	if len(o.Username) > 0 {
+		var err error
+		userIds, err = usernameToIds(guildId, o.Username)
+		if err != nil {
+			return nil, err
+		}
+
+		// TODO: Do this better
+		if len(userIds) == 0 {
+			return nil, errors.New("user not found")
+		}
+	}
+
+	if o.UserId != 0 {
+		userIds = append(userIds, o.UserId)
+	}
+
+	return userIds, nil
+}
+
+// Cursors are opaque to clients, so that the sort key can change without breaking them
+func encodeCursor(cursor dbclient.TranscriptCursor) (string, error) {
+	encoded, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(encoded), nil
+}
+
+func decodeCursor(raw string) (dbclient.TranscriptCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return dbclient.TranscriptCursor{}, err
+	}
+
+	var cursor dbclient.TranscriptCursor
+	if err := json.Unmarshal(decoded, &cursor); err != nil {
+		return dbclient.TranscriptCursor{}, err
+	}
+
+	return cursor, nil
+}
+
 func usernameToIds(guildId uint64, username string) ([]uint64, error) {
 	if len(username) > 32 {
 		return nil, errors.New("username too long")
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	dbclient "github.com/TicketsBot-cloud/dashboard/database"
+	"github.com/TicketsBot-cloud/dashboard/utils"
+	"github.com/TicketsBot-cloud/database"
+	"github.com/gin-gonic/gin"
+)
+
+const ratingCommentMaxLength = 1024
+
+type rateTicketBody struct {
+	Rating  uint8   `json:"rating"`
+	Comment *string `json:"comment"`
+}
+
+// RateTicketHandler allows the opener of a closed ticket to submit or update their rating of it
+func RateTicketHandler(ctx *gin.Context) {
+	guildId := ctx.Keys["guildid"].(uint64)
+	userId := ctx.Keys["userid"].(uint64)
+
+	ticketId, err := strconv.Atoi(ctx.Param("ticketId"))
+	if err != nil {
+		ctx.JSON(400, utils.ErrorStr("Invalid ticket ID"))
+		return
+	}
+
+	var body rateTicketBody
+	if err := ctx.BindJSON(&body); err != nil {
+		ctx.JSON(400, utils.ErrorJson(err))
+		return
+	}
+
+	if body.Rating < 1 || body.Rating > 5 {
+		ctx.JSON(400, utils.ErrorStr("Rating must be between 1 and 5"))
+		return
+	}
+
+	var comment string
+	if body.Comment != nil {
+		comment = strings.TrimSpace(*body.Comment)
+	}
+
+	if len(comment) > ratingCommentMaxLength {
+		ctx.JSON(400, utils.ErrorStr("Comment must be 1024 characters or less"))
+		return
+	}
+
+	ticket, err := dbclient.Client.Tickets.Get(ctx, ticketId, guildId)
+	if err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	// Only the opener may rate the ticket, not staff
+	if ticket.UserId == 0 || ticket.UserId != userId {
+		ctx.JSON(404, utils.ErrorStr("Ticket not found"))
+		return
+	}
+
+	if ticket.Open {
+		ctx.JSON(400, utils.ErrorStr("Ticket must be closed before it can be rated"))
+		return
+	}
+
+	canRate, err := canRateTicket(ctx, ticket)
+	if err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	if !canRate {
+		ctx.JSON(403, utils.ErrorStr("This ticket can no longer be rated"))
+		return
+	}
+
+	if err := dbclient.Client.ServiceRatings.Set(ctx, guildId, ticketId, body.Rating); err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	if comment == "" {
+		err = dbclient.Dashboard.RatingComments.Delete(ctx, guildId, ticketId)
+	} else {
+		err = dbclient.Dashboard.RatingComments.Set(ctx, guildId, ticketId, comment)
+	}
+
+	if err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	ctx.Status(204)
+}
+
+// canRateTicket returns whether the guild accepts feedback, and the ticket was closed within the guild's rating window
+func canRateTicket(ctx context.Context, ticket database.Ticket) (bool, error) {
+	// Tickets closed before close times were recorded can not be rated, as the window can not be checked
+	if ticket.Open || ticket.CloseTime == nil {
+		return false, nil
+	}
+
+	feedbackEnabled, err := dbclient.Client.FeedbackEnabled.Get(ctx, ticket.GuildId)
+	if err != nil {
+		return false, err
+	}
+
+	if !feedbackEnabled {
+		return false, nil
+	}
+
+	window, err := dbclient.Dashboard.RatingWindow.Get(ctx, ticket.GuildId)
+	if err != nil {
+		return false, err
+	}
+
+	return time.Since(*ticket.CloseTime) <= window, nil
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/TicketsBot-cloud/archiverclient"
+	"github.com/TicketsBot-cloud/dashboard/chatreplica"
+	dbclient "github.com/TicketsBot-cloud/dashboard/database"
+	"github.com/TicketsBot-cloud/dashboard/redis"
+	"github.com/TicketsBot-cloud/dashboard/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type redactBody struct {
+	MessageId    uint64  `json:"message_id,string"`
+	AttachmentId *uint64 `json:"attachment_id,string"` // If nil, the whole message is redacted
+}
+
+func RedactTranscriptHandler(ctx *gin.Context) {
+	guildId := ctx.Keys["guildid"].(uint64)
+	userId := ctx.Keys["userid"].(uint64)
+
+	ticketId, err := strconv.Atoi(ctx.Param("ticketId"))
+	if err != nil {
+		ctx.JSON(400, utils.ErrorStr("Invalid ticket ID"))
+		return
+	}
+
+	var body redactBody
+	if err := ctx.BindJSON(&body); err != nil {
+		ctx.JSON(400, utils.ErrorJson(err))
+		return
+	}
+
+	if body.MessageId == 0 {
+		ctx.JSON(400, utils.ErrorStr("Missing message ID"))
+		return
+	}
+
+	ticket, err := dbclient.Client.Tickets.Get(ctx, ticketId, guildId)
+	if err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	if ticket.UserId == 0 || ticket.Open || !ticket.HasTranscript {
+		ctx.JSON(404, utils.ErrorStr("Transcript not found"))
+		return
+	}
+
+	ok, err := redis.Client.TakeTranscriptRedactLock(ctx, guildId, ticketId)
+	if err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	if !ok {
+		ctx.JSON(409, utils.ErrorStr("This transcript is already being redacted, please try again shortly"))
+		return
+	}
+
+	defer redis.Client.ReleaseTranscriptRedactLock(redis.DefaultContext(), guildId, ticketId)
+
+	if err := utils.RedactTranscript(ctx, guildId, ticketId, body.MessageId, body.AttachmentId, userId); err != nil {
+		switch {
+		case errors.Is(err, archiverclient.ErrNotFound):
+			ctx.JSON(404, utils.ErrorStr("Transcript not found"))
+		case errors.Is(err, chatreplica.ErrMessageNotFound):
+			ctx.JSON(404, utils.ErrorStr("Message not found"))
+		case errors.Is(err, chatreplica.ErrAttachmentNotFound):
+			ctx.JSON(404, utils.ErrorStr("Attachment not found"))
+		case errors.Is(err, chatreplica.ErrAlreadyRedacted):
+			ctx.JSON(400, utils.ErrorStr("This has already been redacted"))
+		default:
+			ctx.JSON(500, utils.ErrorJson(err))
+		}
+
+		return
+	}
+
+	ctx.Status(204)
+}
+
+func ListRedactionsHandler(ctx *gin.Context) {
+	guildId := ctx.Keys["guildid"].(uint64)
+
+	ticketId, err := strconv.Atoi(ctx.Param("ticketId"))
+	if err != nil {
+		ctx.JSON(400, utils.ErrorStr("Invalid ticket ID"))
+		return
+	}
+
+	redactions, err := dbclient.Dashboard.TranscriptRedactions.GetByTicket(ctx, guildId, ticketId)
+	if err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	ctx.JSON(200, redactions)
+}
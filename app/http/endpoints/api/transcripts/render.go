@@ -1,16 +1,24 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"strconv"
 
 	"github.com/TicketsBot-cloud/archiverclient"
 	"github.com/TicketsBot-cloud/dashboard/chatreplica"
 	dbclient "github.com/TicketsBot-cloud/dashboard/database"
+	"github.com/TicketsBot-cloud/dashboard/log"
+	"github.com/TicketsBot-cloud/dashboard/redis"
 	"github.com/TicketsBot-cloud/dashboard/utils"
+	v2 "github.com/TicketsBot/logarchiver/pkg/model/v2"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
+// Very long transcripts are rendered on every request, rather than filling Redis
+const maxCachedRenderSize = 8 * 1024 * 1024
+
 func GetTranscriptRenderHandler(ctx *gin.Context) {
 	guildId := ctx.Keys["guildid"].(uint64)
 	userId := ctx.Keys["userid"].(uint64)
@@ -53,8 +61,7 @@ func GetTranscriptRenderHandler(ctx *gin.Context) {
 		}
 	}
 
-	// retrieve ticket messages from bucket
-	transcript, err := utils.ArchiverClient.Get(ctx, guildId, ticketId)
+	html, err := renderTranscript(ctx, guildId, ticketId)
 	if err != nil {
 		if errors.Is(err, archiverclient.ErrNotFound) {
 			ctx.JSON(404, utils.ErrorStr("Transcript not found"))
@@ -65,13 +72,55 @@ func GetTranscriptRenderHandler(ctx *gin.Context) {
 		return
 	}
 
-	// Render
-	payload := chatreplica.FromTranscript(transcript, ticketId)
-	html, err := chatreplica.Render(payload)
+	ctx.Data(200, "text/html", html)
+}
+
+// renderTranscript renders a transcript to HTML, using the cached render if there is one. Closed transcripts only
+// change when redacted or purged, which invalidate the cache. Failures of the cache itself are not fatal, but if the
+// cache generation cannot be read, the render is not cached, as it could outlive a redaction.
+func renderTranscript(ctx context.Context, guildId uint64, ticketId int) ([]byte, error) {
+	logger := log.Logger.With(zap.Uint64("guild_id", guildId), zap.Int("ticket_id", ticketId))
+
+	generation, err := redis.Client.GetTranscriptRenderGeneration(ctx, guildId, ticketId)
+	cacheable := err == nil
 	if err != nil {
-		ctx.JSON(500, utils.ErrorJson(err))
-		return
+		logger.Warn("Failed to get transcript render generation", zap.Error(err))
+	} else {
+		html, ok, err := redis.Client.GetTranscriptRender(ctx, guildId, ticketId, generation, chatreplica.RenderVersions())
+		if err != nil {
+			logger.Warn("Failed to get cached transcript render", zap.Error(err))
+		} else if ok {
+			return html, nil
+		}
 	}
 
-	ctx.Data(200, "text/html", html)
+	transcript, err := utils.ArchiverClient.Get(ctx, guildId, ticketId)
+	if err != nil {
+		return nil, err
+	}
+
+	html, version, err := chatreplica.RenderVersioned(buildPayload(ctx, guildId, ticketId, transcript))
+	if err != nil {
+		return nil, err
+	}
+
+	// Fallback renders are not versioned, so that the render service is retried on the next request
+	if cacheable && version != "" && len(html) <= maxCachedRenderSize {
+		if err := redis.Client.SetTranscriptRender(ctx, guildId, ticketId, generation, version, html); err != nil {
+			logger.Warn("Failed to cache transcript render", zap.Error(err))
+		}
+	}
+
+	return html, nil
+}
+
+// buildPayload converts a transcript for rendering or export, resolving the channels and roles it mentions. A failure
+// to resolve them is not fatal, as the transcript is still readable with raw IDs.
+func buildPayload(ctx context.Context, guildId uint64, ticketId int, transcript v2.Transcript) chatreplica.Payload {
+	payload := chatreplica.FromTranscript(transcript, ticketId)
+	if err := utils.ResolveTranscriptMentions(ctx, guildId, &payload); err != nil {
+		log.Logger.Warn("Failed to resolve transcript mentions", zap.Error(err), zap.Uint64("guild_id", guildId), zap.Int("ticket_id", ticketId))
+	}
+
+	return payload
 }
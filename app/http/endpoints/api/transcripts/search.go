@@ -0,0 +1,267 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/TicketsBot-cloud/archiverclient"
+	dbclient "github.com/TicketsBot-cloud/dashboard/database"
+	"github.com/TicketsBot-cloud/dashboard/utils"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	searchQueryMaxLength = 256
+	snippetsPerTicket    = 3
+
+	searchDisabledMessage = "Transcript search is not enabled for this server"
+)
+
+type searchBody struct {
+	Query string `json:"query"`
+	Page  int    `json:"page"`
+}
+
+type searchResult struct {
+	TicketId int           `json:"ticket_id"`
+	UserId   uint64        `json:"user_id,string"`
+	Matches  []searchMatch `json:"matches"`
+}
+
+type searchMatch struct {
+	MessageId uint64    `json:"message_id,string"`
+	AuthorId  uint64    `json:"author_id,string"`
+	Timestamp time.Time `json:"timestamp"`
+	Snippet   string    `json:"snippet"` // HTML, with matched terms wrapped in <mark> tags
+}
+
+func SearchTranscripts(ctx *gin.Context) {
+	guildId := ctx.Keys["guildid"].(uint64)
+	userId := ctx.Keys["userid"].(uint64)
+
+	var body searchBody
+	if err := ctx.BindJSON(&body); err != nil {
+		ctx.JSON(400, utils.ErrorJson(err))
+		return
+	}
+
+	body.Query = strings.TrimSpace(body.Query)
+	if len(body.Query) == 0 {
+		ctx.JSON(400, utils.ErrorStr("Search query is required"))
+		return
+	}
+
+	if len(body.Query) > searchQueryMaxLength {
+		ctx.JSON(400, utils.ErrorStr("Search query is too long"))
+		return
+	}
+
+	enabled, err := dbclient.Dashboard.TranscriptSearch.IsEnabled(ctx, guildId)
+	if err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	if !enabled {
+		ctx.JSON(400, utils.ErrorStr(searchDisabledMessage))
+		return
+	}
+
+	var offset int
+	if body.Page > 1 {
+		offset = pageLimit * (body.Page - 1)
+	}
+
+	matches, err := dbclient.Dashboard.TranscriptSearch.Search(ctx, guildId, body.Query, pageLimit, offset, snippetsPerTicket)
+	if err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	results := make([]searchResult, 0)
+	for _, match := range matches {
+		if len(results) == 0 || results[len(results)-1].TicketId != match.TicketId {
+			results = append(results, searchResult{
+				TicketId: match.TicketId,
+			})
+		}
+
+		result := &results[len(results)-1]
+		result.Matches = append(result.Matches, searchMatch{
+			MessageId: match.MessageId,
+			AuthorId:  match.AuthorId,
+			Timestamp: match.Timestamp,
+		})
+	}
+
+	// Apply the same checks as viewing the transcript, as panels may restrict which teams can view their tickets. As
+	// results are filtered after paging, a page may contain fewer than pageLimit results.
+	permitted := make([]searchResult, 0, len(results))
+	for _, result := range results {
+		ticket, err := dbclient.Client.Tickets.Get(ctx, result.TicketId, guildId)
+		if err != nil {
+			ctx.JSON(500, utils.ErrorJson(err))
+			return
+		}
+
+		// The ticket may have been purged since it was indexed
+		if ticket.UserId == 0 || ticket.Open {
+			continue
+		}
+
+		hasPermission, requestErr := utils.HasPermissionToViewTicket(ctx, guildId, userId, ticket)
+		if requestErr != nil {
+			ctx.JSON(requestErr.StatusCode, utils.ErrorJson(requestErr))
+			return
+		}
+
+		if hasPermission {
+			result.UserId = ticket.UserId
+			permitted = append(permitted, result)
+		}
+	}
+
+	permitted, err = addSnippets(ctx, guildId, body.Query, permitted)
+	if err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	ctx.JSON(200, permitted)
+}
+
+// addSnippets highlights the matched messages of each result. Message content is not stored in the search index, so
+// it is read from the transcripts themselves. Results whose transcript has since been deleted are dropped.
+func addSnippets(ctx context.Context, guildId uint64, query string, results []searchResult) ([]searchResult, error) {
+	transcripts := make([]map[uint64]string, len(results))
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	for i, result := range results {
+		group.Go(func() error {
+			transcript, err := utils.ArchiverClient.Get(groupCtx, guildId, result.TicketId)
+			if err != nil {
+				if errors.Is(err, archiverclient.ErrNotFound) {
+					return nil
+				}
+
+				return err
+			}
+
+			contents := make(map[uint64]string, len(transcript.Messages))
+			for _, message := range transcript.Messages {
+				contents[message.Id] = message.Content
+			}
+
+			transcripts[i] = contents
+			return nil
+		})
+	}
+
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	var contents []string
+	for i, result := range results {
+		for _, match := range result.Matches {
+			contents = append(contents, transcripts[i][match.MessageId])
+		}
+	}
+
+	snippets, err := dbclient.Dashboard.TranscriptSearch.Highlight(ctx, query, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	withSnippets := make([]searchResult, 0, len(results))
+	for i, result := range results {
+		if transcripts[i] == nil {
+			snippets = snippets[len(result.Matches):]
+			continue
+		}
+
+		for j := range result.Matches {
+			result.Matches[j].Snippet = utils.FormatSearchSnippet(snippets[j])
+		}
+
+		snippets = snippets[len(result.Matches):]
+		withSnippets = append(withSnippets, result)
+	}
+
+	return withSnippets, nil
+}
+
+// Each call indexes a batch of transcripts closed before search was enabled, so that the frontend can show progress
+// and the request does not time out.
+const indexBatchSize = 25
+
+func IndexTranscripts(ctx *gin.Context) {
+	guildId := ctx.Keys["guildid"].(uint64)
+
+	indexed, failed, more, err := utils.BackfillTranscripts(ctx, guildId, indexBatchSize)
+	if err != nil {
+		if errors.Is(err, dbclient.ErrTranscriptSearchDisabled) {
+			ctx.JSON(400, utils.ErrorStr(searchDisabledMessage))
+		} else {
+			ctx.JSON(500, utils.ErrorJson(err))
+		}
+
+		return
+	}
+
+	ctx.JSON(200, gin.H{
+		"success":   true,
+		"indexed":   indexed,
+		"failed":    failed,
+		"remaining": more,
+	})
+}
+
+type searchSettings struct {
+	Enabled bool `json:"enabled"`
+}
+
+func GetSearchSettings(ctx *gin.Context) {
+	guildId := ctx.Keys["guildid"].(uint64)
+
+	enabled, err := dbclient.Dashboard.TranscriptSearch.IsEnabled(ctx, guildId)
+	if err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	ctx.JSON(200, searchSettings{
+		Enabled: enabled,
+	})
+}
+
+// UpdateSearchSettings opts the guild in to or out of transcript search. The search index holds every word of the
+// guild's transcripts unencrypted, so admins must choose to enable it, and disabling it deletes the index. Existing
+// transcripts are indexed once enabled by calling IndexTranscripts.
+func UpdateSearchSettings(ctx *gin.Context) {
+	guildId := ctx.Keys["guildid"].(uint64)
+	userId := ctx.Keys["userid"].(uint64)
+
+	var body searchSettings
+	if err := ctx.BindJSON(&body); err != nil {
+		ctx.JSON(400, utils.ErrorJson(err))
+		return
+	}
+
+	var err error
+	if body.Enabled {
+		err = dbclient.Dashboard.TranscriptSearch.Enable(ctx, guildId, userId)
+	} else {
+		err = dbclient.Dashboard.TranscriptSearch.Disable(ctx, guildId)
+	}
+
+	if err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	ctx.JSON(200, body)
+}
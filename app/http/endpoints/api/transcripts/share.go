@@ -0,0 +1,253 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/TicketsBot-cloud/archiverclient"
+	dbclient "github.com/TicketsBot-cloud/dashboard/database"
+	"github.com/TicketsBot-cloud/dashboard/internal/api"
+	"github.com/TicketsBot-cloud/dashboard/utils"
+	"github.com/TicketsBot-cloud/database"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultShareLinkExpiry = 24 * time.Hour
+	minShareLinkExpiry     = 5 * time.Minute
+	maxShareLinkExpiry     = 30 * 24 * time.Hour
+)
+
+type createShareLinkBody struct {
+	ExpiresIn int  `json:"expires_in"` // Seconds
+	SingleUse bool `json:"single_use"`
+}
+
+type createShareLinkResponse struct {
+	dbclient.TranscriptShareLink
+	Token string `json:"token"`
+}
+
+func CreateShareLinkHandler(ctx *gin.Context) {
+	guildId := ctx.Keys["guildid"].(uint64)
+	userId := ctx.Keys["userid"].(uint64)
+
+	ticketId, err := strconv.Atoi(ctx.Param("ticketId"))
+	if err != nil {
+		ctx.JSON(400, utils.ErrorStr("Invalid ticket ID"))
+		return
+	}
+
+	var body createShareLinkBody
+	if err := ctx.BindJSON(&body); err != nil {
+		ctx.JSON(400, utils.ErrorJson(err))
+		return
+	}
+
+	expiry := defaultShareLinkExpiry
+	if body.ExpiresIn != 0 {
+		expiry = time.Duration(body.ExpiresIn) * time.Second
+	}
+
+	if expiry < minShareLinkExpiry || expiry > maxShareLinkExpiry {
+		ctx.JSON(400, utils.ErrorStr("Share links must expire between 5 minutes and 30 days after creation"))
+		return
+	}
+
+	ticket, err := dbclient.Client.Tickets.Get(ctx, ticketId, guildId)
+	if err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	if ticket.UserId == 0 || ticket.Open || !ticket.HasTranscript {
+		ctx.JSON(404, utils.ErrorStr("Transcript not found"))
+		return
+	}
+
+	// Staff may only share transcripts they can view themselves
+	hasPermission, requestErr := utils.HasPermissionToViewTicket(ctx, guildId, userId, ticket)
+	if requestErr != nil {
+		ctx.JSON(requestErr.StatusCode, utils.ErrorJson(requestErr))
+		return
+	}
+
+	if !hasPermission {
+		ctx.JSON(403, utils.ErrorStr("You do not have permission to view this transcript"))
+		return
+	}
+
+	now := time.Now()
+	link := dbclient.TranscriptShareLink{
+		Id:        uuid.New(),
+		GuildId:   guildId,
+		TicketId:  ticketId,
+		CreatedBy: userId,
+		CreatedAt: now,
+		ExpiresAt: now.Add(expiry),
+		SingleUse: body.SingleUse,
+	}
+
+	token, err := utils.GenerateTranscriptShareToken(link.Id, link.ExpiresAt)
+	if err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	if err := dbclient.Dashboard.TranscriptShareLinks.Create(ctx, link); err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	ctx.JSON(200, createShareLinkResponse{
+		TranscriptShareLink: link,
+		Token:               token,
+	})
+}
+
+// ListShareLinksHandler returns the guild's active share links, for the tickets the user can view
+func ListShareLinksHandler(ctx *gin.Context) {
+	guildId := ctx.Keys["guildid"].(uint64)
+	userId := ctx.Keys["userid"].(uint64)
+
+	links, err := dbclient.Dashboard.TranscriptShareLinks.GetByGuild(ctx, guildId)
+	if err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	// A guild usually has few active links, often several for the same ticket
+	canView := make(map[int]bool)
+
+	permitted := make([]dbclient.TranscriptShareLink, 0, len(links))
+	for _, link := range links {
+		hasPermission, ok := canView[link.TicketId]
+		if !ok {
+			var requestErr *api.RequestError
+			hasPermission, requestErr = canViewShareLinkTicket(ctx, guildId, userId, link.TicketId)
+			if requestErr != nil {
+				ctx.JSON(requestErr.StatusCode, utils.ErrorJson(requestErr))
+				return
+			}
+
+			canView[link.TicketId] = hasPermission
+		}
+
+		if hasPermission {
+			permitted = append(permitted, link)
+		}
+	}
+
+	ctx.JSON(200, permitted)
+}
+
+func RevokeShareLinkHandler(ctx *gin.Context) {
+	guildId := ctx.Keys["guildid"].(uint64)
+	userId := ctx.Keys["userid"].(uint64)
+
+	shareId, err := uuid.Parse(ctx.Param("shareId"))
+	if err != nil {
+		ctx.JSON(400, utils.ErrorStr("Invalid share link ID"))
+		return
+	}
+
+	link, ok, err := dbclient.Dashboard.TranscriptShareLinks.Get(ctx, shareId)
+	if err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	if !ok || link.GuildId != guildId {
+		ctx.JSON(404, utils.ErrorStr("Share link not found"))
+		return
+	}
+
+	// Staff may only revoke links to transcripts they can view themselves
+	hasPermission, requestErr := canViewShareLinkTicket(ctx, guildId, userId, link.TicketId)
+	if requestErr != nil {
+		ctx.JSON(requestErr.StatusCode, utils.ErrorJson(requestErr))
+		return
+	}
+
+	if !hasPermission {
+		ctx.JSON(403, utils.ErrorStr("You do not have permission to view this transcript"))
+		return
+	}
+
+	found, err := dbclient.Dashboard.TranscriptShareLinks.Delete(ctx, guildId, shareId)
+	if err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	if !found {
+		ctx.JSON(404, utils.ErrorStr("Share link not found"))
+		return
+	}
+
+	ctx.Status(204)
+}
+
+// canViewShareLinkTicket returns whether the user can view the transcript a share link points to. Links to tickets
+// that no longer exist can only be seen by those who can view every ticket.
+func canViewShareLinkTicket(ctx context.Context, guildId, userId uint64, ticketId int) (bool, *api.RequestError) {
+	ticket, err := dbclient.Client.Tickets.Get(ctx, ticketId, guildId)
+	if err != nil {
+		return false, api.NewDatabaseError(err)
+	}
+
+	if ticket.UserId == 0 {
+		ticket = database.Ticket{Id: ticketId, GuildId: guildId}
+	}
+
+	return utils.HasPermissionToViewTicket(ctx, guildId, userId, ticket)
+}
+
+// GetSharedTranscriptHandler renders a transcript for anyone holding a valid share token, without authentication
+func GetSharedTranscriptHandler(ctx *gin.Context) {
+	shareId, err := utils.ParseTranscriptShareToken(ctx.Param("token"))
+	if err != nil {
+		ctx.JSON(404, utils.ErrorStr("Share link is invalid or has expired"))
+		return
+	}
+
+	link, ok, err := dbclient.Dashboard.TranscriptShareLinks.Get(ctx, shareId)
+	if err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	}
+
+	if !ok || !link.Usable() {
+		ctx.JSON(404, utils.ErrorStr("Share link is invalid or has expired"))
+		return
+	}
+
+	html, err := renderTranscript(ctx, link.GuildId, link.TicketId)
+	if err != nil {
+		if errors.Is(err, archiverclient.ErrNotFound) {
+			ctx.JSON(404, utils.ErrorStr("Transcript not found"))
+		} else {
+			ctx.JSON(500, utils.ErrorJson(err))
+		}
+
+		return
+	}
+
+	// Only consume the link once the transcript has rendered, so that a transient failure does not use up a single-use
+	// link. Use is atomic, so if the link was used or revoked during the render, it is not served.
+	if _, ok, err := dbclient.Dashboard.TranscriptShareLinks.Use(ctx, shareId); err != nil {
+		ctx.JSON(500, utils.ErrorJson(err))
+		return
+	} else if !ok {
+		ctx.JSON(404, utils.ErrorStr("Share link is invalid or has expired"))
+		return
+	}
+
+	// Shared transcripts must not be stored by intermediaries, as the link may be single-use or revoked
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Referrer-Policy", "no-referrer")
+	ctx.Data(200, "text/html", html)
+}
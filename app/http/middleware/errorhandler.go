@@ -21,12 +21,30 @@ func (cw copyWriter) Write(b []byte) (int, error) {
 	return cw.buf.Write(b)
 }
 
+const unbufferedKey = "unbuffered"
+
+// Unbuffered exempts a route from ErrorHandler, for responses that must reach the client as they are written, such as
+// event streams. It must be registered on the route itself, after ErrorHandler has wrapped the writer.
+func Unbuffered(c *gin.Context) {
+	if cw, ok := c.Writer.(*copyWriter); ok {
+		c.Writer = cw.ResponseWriter
+	}
+
+	c.Set(unbufferedKey, true)
+	c.Next()
+}
+
 func ErrorHandler(c *gin.Context) {
 	cw := &copyWriter{buf: &bytes.Buffer{}, ResponseWriter: c.Writer}
 	c.Writer = cw
 
 	c.Next()
 
+	// The response has already been written directly to the client
+	if c.GetBool(unbufferedKey) {
+		return
+	}
+
 	if len(c.Errors) > 0 {
 		var message string
 
@@ -94,11 +94,17 @@ func getKey(ctx *gin.Context, rlType RateLimitType, limit redis_rate.Limit) (str
 		key = strconv.FormatUint(guildId.(uint64), 10)
 	}
 
+	return RateLimitKey(rlType, key, limit, ctx.FullPath()), false
+}
+
+// RateLimitKey returns the bucket CreateRateLimiter uses for a route, so that other transports performing the same
+// action, such as live-chat, can share its limit.
+func RateLimitKey(rlType RateLimitType, key string, limit redis_rate.Limit, fullPath string) string {
 	target := fmt.Sprintf("%d:%s", rlType, key)
 	bucket := fmt.Sprintf("%d/%d", limit.Rate, limit.Period.Milliseconds())
-	full := fmt.Sprintf("%s:%s:%s", target, bucket, ctx.FullPath())
+	full := fmt.Sprintf("%s:%s:%s", target, bucket, fullPath)
 
-	return strconv.FormatUint(uint64(hash(full)), 16), false
+	return strconv.FormatUint(uint64(hash(full)), 16)
 }
 
 func hash(str string) uint32 {
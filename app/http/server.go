@@ -77,6 +77,9 @@ func StartServer(logger *zap.Logger, sm *livechat.SocketManager) {
 		ctx.String(200, "Disallow: /")
 	})
 
+	// Transcript share links are opened by people without a dashboard account
+	router.GET("/transcripts/shared/:token", rl(middleware.RateLimitTypeIp, 10, 10*time.Second), api_transcripts.GetSharedTranscriptHandler)
+
 	router.POST("/callback", middleware.VerifyXTicketsHeader, root.CallbackHandler)
 	router.POST("/logout", middleware.VerifyXTicketsHeader, middleware.AuthenticateToken, root.LogoutHandler)
 
@@ -121,6 +124,9 @@ func StartServer(logger *zap.Logger, sm *livechat.SocketManager) {
 		// Must be readable to load transcripts page
 		guildAuthApiSupport.GET("/settings", api_settings.GetSettingsHandler)
 		guildAuthApiAdmin.POST("/settings", api_settings.UpdateSettingsHandler)
+		guildAuthApiAdmin.GET("/settings/retention", api_settings.GetRetentionHandler)
+		guildAuthApiAdmin.POST("/settings/retention", api_settings.UpdateRetentionHandler)
+		guildAuthApiAdmin.GET("/settings/retention/log", api_settings.GetRetentionLogHandler)
 
 		guildAuthApiAdmin.POST("/import", api_import.ImportHandler)
 		guildAuthApiAdmin.GET("/import/runs", api_import.GetRuns)
@@ -158,18 +164,57 @@ func StartServer(logger *zap.Logger, sm *livechat.SocketManager) {
 			api_transcripts.ListTranscripts,
 		)
 
+		guildAuthApiSupport.GET("/transcripts",
+			rl(middleware.RateLimitTypeUser, 5, 5*time.Second),
+			rl(middleware.RateLimitTypeUser, 20, time.Minute),
+			api_transcripts.ListTranscriptsPage,
+		)
+
+		guildAuthApiSupport.POST("/transcripts/search",
+			rl(middleware.RateLimitTypeUser, 5, 5*time.Second),
+			rl(middleware.RateLimitTypeUser, 20, time.Minute),
+			api_transcripts.SearchTranscripts,
+		)
+		guildAuthApiAdmin.POST("/transcripts/search/index", rl(middleware.RateLimitTypeGuild, 10, time.Minute), api_transcripts.IndexTranscripts)
+		guildAuthApiSupport.GET("/transcripts/search/settings", api_transcripts.GetSearchSettings)
+		guildAuthApiAdmin.POST("/transcripts/search/settings", rl(middleware.RateLimitTypeGuild, 5, time.Minute), api_transcripts.UpdateSearchSettings)
+
+		guildAuthApiAdmin.GET("/transcripts/archives", api_transcripts.ListArchivesHandler)
+		guildAuthApiAdmin.GET("/transcripts/archives/:archiveId", api_transcripts.GetArchiveHandler)
+		guildAuthApiAdmin.POST("/transcripts/archives", rl(middleware.RateLimitTypeGuild, 3, 10*time.Minute), api_transcripts.CreateArchiveHandler)
+
+		guildAuthApiSupport.GET("/transcripts/shares", api_transcripts.ListShareLinksHandler)
+		guildAuthApiSupport.DELETE("/transcripts/shares/:shareId", api_transcripts.RevokeShareLinkHandler)
+		guildAuthApiSupport.POST("/transcripts/:ticketId/share", rl(middleware.RateLimitTypeUser, 10, time.Minute), api_transcripts.CreateShareLinkHandler)
+		guildAuthApiAdmin.GET("/transcripts/:ticketId/redactions", api_transcripts.ListRedactionsHandler)
+		guildAuthApiAdmin.POST("/transcripts/:ticketId/redactions", rl(middleware.RateLimitTypeGuild, 10, time.Minute), api_transcripts.RedactTranscriptHandler)
+
 		// Allow regular users to get their own transcripts, make sure you check perms inside
 		guildApiNoAuth.GET("/transcripts/:ticketId", rl(middleware.RateLimitTypeGuild, 10, 10*time.Second), api_transcripts.GetTranscriptHandler)
 		guildApiNoAuth.GET("/transcripts/:ticketId/render", rl(middleware.RateLimitTypeGuild, 10, 10*time.Second), api_transcripts.GetTranscriptRenderHandler)
+		guildApiNoAuth.PUT("/transcripts/:ticketId/rating", rl(middleware.RateLimitTypeUser, 5, time.Minute), api_transcripts.RateTicketHandler)
 
 		guildAuthApiSupport.GET("/tickets", api_ticket.GetTickets)
+		guildAuthApiSupport.POST("/tickets", rl(middleware.RateLimitTypeGuild, 5, time.Second*30), api_ticket.OpenTicket)
 		guildAuthApiSupport.GET("/tickets/:ticketId", api_ticket.GetTicket)
 		guildAuthApiSupport.POST("/tickets/:ticketId", rl(middleware.RateLimitTypeGuild, 5, time.Second*5), api_ticket.SendMessage)
 		guildAuthApiSupport.POST("/tickets/:ticketId/tag", rl(middleware.RateLimitTypeGuild, 5, time.Second*5), api_ticket.SendTag)
 		guildAuthApiSupport.DELETE("/tickets/:ticketId", api_ticket.CloseTicket)
+		guildAuthApiSupport.POST("/tickets/:ticketId/claim", rl(middleware.RateLimitTypeGuild, 5, time.Second*5), api_ticket.ClaimTicket)
+		guildAuthApiSupport.DELETE("/tickets/:ticketId/claim", rl(middleware.RateLimitTypeGuild, 5, time.Second*5), api_ticket.UnclaimTicket)
+		guildAuthApiSupport.POST("/tickets/:ticketId/transfer", rl(middleware.RateLimitTypeGuild, 5, time.Second*5), api_ticket.TransferTicket)
+		guildAuthApiSupport.PUT("/tickets/:ticketId/participants/:snowflake", rl(middleware.RateLimitTypeGuild, 5, time.Second*5), api_ticket.AddParticipant)
+		guildAuthApiSupport.DELETE("/tickets/:ticketId/participants/:snowflake", rl(middleware.RateLimitTypeGuild, 5, time.Second*5), api_ticket.RemoveParticipant)
+		guildAuthApiSupport.GET("/tickets/:ticketId/notes", api_ticket.ListNotes)
+		guildAuthApiSupport.POST("/tickets/:ticketId/notes", rl(middleware.RateLimitTypeUser, 10, time.Minute), api_ticket.CreateNote)
+		guildAuthApiSupport.PATCH("/tickets/:ticketId/notes/:noteId", rl(middleware.RateLimitTypeUser, 10, time.Minute), api_ticket.UpdateNote)
+		guildAuthApiSupport.DELETE("/tickets/:ticketId/notes/:noteId", api_ticket.DeleteNote)
 
 		// Websockets do not support headers: so we must implement authentication over the WS connection
 		router.GET("/api/:id/tickets/:ticketId/live-chat", livechat.GetLiveChatHandler(sm))
+		router.GET("/api/:id/tickets/live", livechat.GetTicketListHandler(sm))
+		// Fallback for clients that cannot open a websocket. Make sure you check perms inside
+		guildApiNoAuth.GET("/tickets/:ticketId/live-chat/sse", middleware.Unbuffered, livechat.GetLiveChatSSEHandler(sm))
 
 		guildAuthApiSupport.GET("/tags", api_tags.TagsListHandler)
 		guildAuthApiSupport.PUT("/tags", api_tags.CreateTag)
@@ -203,6 +248,7 @@ func StartServer(logger *zap.Logger, sm *livechat.SocketManager) {
 	{
 		userGroup.POST("/guilds/reload", api.ReloadGuildsHandler)
 		userGroup.GET("/permissionlevel", api.GetPermissionLevel)
+		userGroup.GET("/tickets", rl(middleware.RateLimitTypeUser, 10, 10*time.Second), api_transcripts.ListSelfTranscripts)
 
 		{
 			whitelabelGroup := userGroup.Group("/whitelabel", middleware.VerifyWhitelabel(true))
@@ -0,0 +1,42 @@
+package validation
+
+import (
+	"github.com/TicketsBot-cloud/dashboard/utils/types"
+)
+
+// Discord rejects embeds whose title, description, field, footer and author text exceed this many characters combined
+const embedMaxCharacters = 6000
+
+// ValidateMessageEmbed checks the limits of an embed sent as a message that cannot be expressed with struct tags, and
+// so must be called in addition to validating the struct.
+func ValidateMessageEmbed(e *types.CustomEmbed) error {
+	if e == nil || isEmptyEmbed(e) {
+		return NewInvalidInputError("Your embed message does not contain any content")
+	}
+
+	if embedCharacters(e) > embedMaxCharacters {
+		return NewInvalidInputErrorf("Your embed message cannot exceed %d characters in total", embedMaxCharacters)
+	}
+
+	return nil
+}
+
+func isEmptyEmbed(e *types.CustomEmbed) bool {
+	return e.Title == nil && e.Description == nil && len(e.Fields) == 0 && e.ImageUrl == nil && e.ThumbnailUrl == nil &&
+		e.Author.Name == nil && e.Footer.Text == nil
+}
+
+func embedCharacters(e *types.CustomEmbed) int {
+	var count int
+	for _, s := range []*string{e.Title, e.Description, e.Footer.Text, e.Author.Name} {
+		if s != nil {
+			count += len([]rune(*s))
+		}
+	}
+
+	for _, field := range e.Fields {
+		count += len([]rune(field.Name)) + len([]rune(field.Value))
+	}
+
+	return count
+}
@@ -0,0 +1,50 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/TicketsBot-cloud/dashboard/utils/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmbedEmpty(t *testing.T) {
+	assert.Error(t, ValidateMessageEmbed(nil))
+	assert.Error(t, ValidateMessageEmbed(&types.CustomEmbed{}))
+}
+
+func TestEmbedFooterOnly(t *testing.T) {
+	text := "footer"
+	assert.NoError(t, ValidateMessageEmbed(&types.CustomEmbed{
+		Footer: types.Footer{Text: &text},
+	}))
+}
+
+func TestEmbedWithinLimit(t *testing.T) {
+	description := strings.Repeat("a", 4096)
+	title := strings.Repeat("a", 255)
+
+	assert.NoError(t, ValidateMessageEmbed(&types.CustomEmbed{
+		Title:       &title,
+		Description: &description,
+	}))
+}
+
+func TestEmbedExceedsLimit(t *testing.T) {
+	description := strings.Repeat("a", 4096)
+	fields := make([]types.Field, 2)
+	for i := range fields {
+		fields[i] = types.Field{
+			Name:  "name",
+			Value: strings.Repeat("a", 1024),
+		}
+	}
+
+	err := ValidateMessageEmbed(&types.CustomEmbed{
+		Description: &description,
+		Fields:      fields,
+	})
+
+	var validationError *InvalidInputError
+	assert.ErrorAs(t, err, &validationError)
+}
@@ -0,0 +1,268 @@
+package chatreplica
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rxdn/gdl/objects/channel/embed"
+)
+
+type ExportFormat string
+
+const (
+	ExportFormatText     ExportFormat = "text"
+	ExportFormatMarkdown ExportFormat = "markdown"
+	ExportFormatJsonl    ExportFormat = "jsonl"
+)
+
+func (f ExportFormat) IsValid() bool {
+	switch f {
+	case ExportFormatText, ExportFormatMarkdown, ExportFormatJsonl:
+		return true
+	default:
+		return false
+	}
+}
+
+func (f ExportFormat) ContentType() string {
+	switch f {
+	case ExportFormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	case ExportFormatJsonl:
+		return "application/jsonl; charset=utf-8"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+func (f ExportFormat) Extension() string {
+	switch f {
+	case ExportFormatMarkdown:
+		return "md"
+	case ExportFormatJsonl:
+		return "jsonl"
+	default:
+		return "txt"
+	}
+}
+
+const exportTimeFormat = "2006-01-02 15:04:05 MST"
+
+var (
+	plainMentionRegex   = regexp.MustCompile(`<(@!?|@&|#)(\d+)>`)
+	plainEmojiRegex     = regexp.MustCompile(`<a?:(\w+):\d+>`)
+	plainTimestampRegex = regexp.MustCompile(`<t:(-?\d+)(?::([tTdDfFR]))?>`)
+)
+
+type jsonlMessage struct {
+	Id          uint64        `json:"id,string"`
+	AuthorId    uint64        `json:"author_id,string"`
+	Author      string        `json:"author"`
+	Timestamp   time.Time     `json:"timestamp"`
+	Content     string        `json:"content"`
+	Attachments []string      `json:"attachments,omitempty"`
+	Embeds      []embed.Embed `json:"embeds,omitempty"`
+}
+
+// Export converts a transcript into a format suitable for pasting into other tools. Mentions are replaced with the
+// names of the users, channels and roles they refer to, and attachments are listed by URL.
+func Export(payload Payload, format ExportFormat) ([]byte, error) {
+	switch format {
+	case ExportFormatText:
+		return exportText(payload), nil
+	case ExportFormatMarkdown:
+		return exportMarkdown(payload), nil
+	case ExportFormatJsonl:
+		return exportJsonl(payload)
+	default:
+		return nil, fmt.Errorf("unknown export format %s", format)
+	}
+}
+
+func exportText(payload Payload) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("#%s\n\n", payload.ChannelName))
+
+	for _, msg := range payload.Messages {
+		buf.WriteString(fmt.Sprintf("[%s] %s: %s\n", formatMessageTime(msg), authorName(payload.Entities, msg.Author), resolveMentions(msg.Content, payload.Entities)))
+
+		for _, attachment := range msg.Attachments {
+			if IsRedactedAttachment(attachment) {
+				buf.WriteString(fmt.Sprintf("    Attachment: %s\n", RedactedPlaceholder))
+			} else {
+				buf.WriteString(fmt.Sprintf("    Attachment: %s (%s)\n", attachment.Filename, attachment.Url))
+			}
+		}
+
+		for _, e := range msg.Embeds {
+			for _, line := range embedLines(e, payload.Entities) {
+				buf.WriteString(fmt.Sprintf("    %s\n", line))
+			}
+		}
+	}
+
+	return buf.Bytes()
+}
+
+func exportMarkdown(payload Payload) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("# #%s\n", payload.ChannelName))
+
+	for _, msg := range payload.Messages {
+		buf.WriteString(fmt.Sprintf("\n**%s** — %s\n", escapeMarkdown(authorName(payload.Entities, msg.Author)), formatMessageTime(msg)))
+
+		// Content is already Discord flavoured markdown, so is not escaped
+		if msg.Content != "" {
+			buf.WriteString(resolveMentions(msg.Content, payload.Entities))
+			buf.WriteString("\n")
+		}
+
+		for _, attachment := range msg.Attachments {
+			if IsRedactedAttachment(attachment) {
+				buf.WriteString(fmt.Sprintf("- *%s*\n", escapeMarkdown(RedactedPlaceholder)))
+			} else {
+				buf.WriteString(fmt.Sprintf("- [%s](%s)\n", escapeMarkdown(attachment.Filename), attachment.Url))
+			}
+		}
+
+		for _, e := range msg.Embeds {
+			for _, line := range embedLines(e, payload.Entities) {
+				buf.WriteString(fmt.Sprintf("> %s\n", line))
+			}
+		}
+	}
+
+	return buf.Bytes()
+}
+
+func exportJsonl(payload Payload) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+
+	for _, msg := range payload.Messages {
+		attachments := make([]string, len(msg.Attachments))
+		for i, attachment := range msg.Attachments {
+			if IsRedactedAttachment(attachment) {
+				attachments[i] = RedactedPlaceholder
+			} else {
+				attachments[i] = attachment.Url
+			}
+		}
+
+		// Encode writes a trailing newline after each message
+		if err := encoder.Encode(jsonlMessage{
+			Id:          msg.Id,
+			AuthorId:    msg.Author,
+			Author:      authorName(payload.Entities, msg.Author),
+			Timestamp:   time.UnixMilli(msg.Time).UTC(),
+			Content:     resolveMentions(msg.Content, payload.Entities),
+			Attachments: attachments,
+			Embeds:      msg.Embeds,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// embedLines flattens an embed into lines of text, as neither format can represent its layout
+func embedLines(e embed.Embed, entities Entities) []string {
+	var lines []string
+	if e.Author != nil && e.Author.Name != "" {
+		lines = append(lines, e.Author.Name)
+	}
+
+	if e.Title != "" {
+		lines = append(lines, resolveMentions(e.Title, entities))
+	}
+
+	if e.Description != "" {
+		lines = append(lines, strings.Split(resolveMentions(e.Description, entities), "\n")...)
+	}
+
+	for _, field := range e.Fields {
+		lines = append(lines, fmt.Sprintf("%s: %s", resolveMentions(field.Name, entities), resolveMentions(field.Value, entities)))
+	}
+
+	if e.Image != nil && e.Image.Url != "" {
+		lines = append(lines, e.Image.Url)
+	}
+
+	if e.Footer != nil && e.Footer.Text != "" {
+		lines = append(lines, e.Footer.Text)
+	}
+
+	return lines
+}
+
+func resolveMentions(content string, entities Entities) string {
+	content = replaceAllSubmatchFunc(plainMentionRegex, content, func(groups []string) string {
+		id := groups[2]
+
+		switch groups[1] {
+		case "#":
+			if channel, ok := entities.Channels[id]; ok {
+				return "#" + channel.Name
+			}
+
+			return "#deleted-channel"
+		case "@&":
+			if role, ok := entities.Roles[id]; ok {
+				return "@" + role.Name
+			}
+
+			return "@deleted-role"
+		default:
+			if user, ok := entities.Users[id]; ok {
+				return "@" + user.Username
+			}
+
+			return "@unknown-user"
+		}
+	})
+
+	content = plainEmojiRegex.ReplaceAllString(content, ":$1:")
+
+	return replaceAllSubmatchFunc(plainTimestampRegex, content, func(groups []string) string {
+		seconds, err := strconv.ParseInt(groups[1], 10, 64)
+		if err != nil {
+			return groups[0]
+		}
+
+		return time.Unix(seconds, 0).UTC().Format(exportTimeFormat)
+	})
+}
+
+func authorName(entities Entities, userId uint64) string {
+	if user, ok := entities.Users[strconv.FormatUint(userId, 10)]; ok {
+		return user.Username
+	}
+
+	return "Unknown User"
+}
+
+func formatMessageTime(msg Message) string {
+	return time.UnixMilli(msg.Time).UTC().Format(exportTimeFormat)
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"*", `\*`,
+	"_", `\_`,
+	"~", `\~`,
+	"`", "\\`",
+	"|", `\|`,
+	"[", `\[`,
+	"]", `\]`,
+)
+
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
@@ -0,0 +1,56 @@
+package chatreplica
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/rxdn/gdl/objects/channel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testPayload = Payload{
+	ChannelName: "ticket-1",
+	Entities:    testEntities,
+	Messages: []Message{
+		{Id: 1, Author: 1, Time: 0, Content: "hello <#2> <@&3>"},
+		{Id: 2, Author: 4, Time: 1000, Attachments: []channel.Attachment{
+			{Filename: "file.txt", Url: "https://cdn.discordapp.com/file.txt"},
+		}},
+	},
+}
+
+func TestExportText(t *testing.T) {
+	exported, err := Export(testPayload, ExportFormatText)
+	require.NoError(t, err)
+
+	assert.Equal(t, `#ticket-1
+
+[1970-01-01 00:00:00 UTC] user_name: hello #general @Support
+[1970-01-01 00:00:01 UTC] Unknown User: 
+    Attachment: file.txt (https://cdn.discordapp.com/file.txt)
+`, string(exported))
+}
+
+func TestExportMarkdown(t *testing.T) {
+	exported, err := Export(testPayload, ExportFormatMarkdown)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(exported), `**user\_name** — 1970-01-01 00:00:00 UTC`)
+	assert.Contains(t, string(exported), "- [file.txt](https://cdn.discordapp.com/file.txt)")
+}
+
+func TestExportJsonl(t *testing.T) {
+	exported, err := Export(testPayload, ExportFormatJsonl)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(string(exported), "\n"), "\n")
+	require.Len(t, lines, 2)
+
+	var msg jsonlMessage
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &msg))
+	assert.Equal(t, uint64(2), msg.Id)
+	assert.Equal(t, "Unknown User", msg.Author)
+	assert.Equal(t, []string{"https://cdn.discordapp.com/file.txt"}, msg.Attachments)
+}
@@ -0,0 +1,247 @@
+package chatreplica
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// placeholderMarker delimits the index of a token that has already been rendered, so that the formatting rules can
+// not be applied inside of it, e.g. underscores in a URL or emoji name. It is stripped from content before rendering.
+const placeholderMarker = "\x00"
+
+var (
+	codeBlockRegex  = regexp.MustCompile("(?s)```(?:([a-zA-Z0-9_+-]+)\n)?(.*?)```")
+	inlineCodeRegex = regexp.MustCompile("``([^`]+)``|`([^`]+)`")
+
+	// Matched against escaped content, so < and > appear as &lt; and &gt;
+	linkRegex      = regexp.MustCompile(`\[([^\]\n]+)\]\((https?://[^\s)]+)\)|&lt;(https?://[^\s]+?)&gt;|(https?://[^\s<]+[^\s<.,:;"')\]])`)
+	mentionRegex   = regexp.MustCompile(`&lt;(@!?|@&amp;|#)(\d+)&gt;`)
+	emojiRegex     = regexp.MustCompile(`&lt;(a?):(\w+):(\d+)&gt;`)
+	timestampRegex = regexp.MustCompile(`&lt;t:(-?\d+)(?::([tTdDfFR]))?&gt;`)
+
+	placeholderRegex = regexp.MustCompile(placeholderMarker + `(\d+)` + placeholderMarker)
+
+	inlineRules = []struct {
+		regex       *regexp.Regexp
+		replacement string
+	}{
+		{regexp.MustCompile(`\*\*\*(.+?)\*\*\*`), "<strong><em>$1</em></strong>"},
+		{regexp.MustCompile(`\*\*(.+?)\*\*`), "<strong>$1</strong>"},
+		{regexp.MustCompile(`__(.+?)__`), "<u>$1</u>"},
+		{regexp.MustCompile(`\*([^*\s](?:[^*]*[^*\s])?)\*`), "<em>$1</em>"},
+		{regexp.MustCompile(`\b_([^_]+)_\b`), "<em>$1</em>"},
+		{regexp.MustCompile(`~~(.+?)~~`), "<s>$1</s>"},
+		{regexp.MustCompile(`\|\|(.+?)\|\|`), `<span class="spoiler">$1</span>`},
+	}
+
+	headerRegex = regexp.MustCompile(`^(#{1,3}) (.+)$`)
+)
+
+var timestampFormats = map[string]string{
+	"t": "15:04",
+	"T": "15:04:05",
+	"d": "02/01/2006",
+	"D": "2 January 2006",
+	"f": "2 January 2006 15:04",
+	"F": "Monday, 2 January 2006 15:04",
+	"R": "2 January 2006 15:04", // Relative timestamps would be out of date as soon as the transcript is rendered
+}
+
+type markdownRenderer struct {
+	entities Entities
+	tokens   []string
+}
+
+// renderMarkdown converts Discord flavoured markdown into HTML. All content is escaped, and only http(s) links are
+// rendered, so the output is safe to embed in a page.
+func renderMarkdown(content string, entities Entities) string {
+	r := &markdownRenderer{
+		entities: entities,
+	}
+
+	content = strings.ReplaceAll(content, placeholderMarker, "")
+
+	// Code blocks are extracted first, as no other formatting applies within them
+	content = replaceAllSubmatchFunc(codeBlockRegex, content, func(groups []string) string {
+		code := strings.Trim(groups[2], "\n")
+
+		class := ""
+		if groups[1] != "" {
+			class = fmt.Sprintf(` class="language-%s"`, html.EscapeString(groups[1]))
+		}
+
+		return r.token(fmt.Sprintf(`<pre><code%s>%s</code></pre>`, class, html.EscapeString(code)))
+	})
+
+	content = replaceAllSubmatchFunc(inlineCodeRegex, content, func(groups []string) string {
+		code := groups[1]
+		if code == "" {
+			code = groups[2]
+		}
+
+		return r.token(fmt.Sprintf(`<code>%s</code>`, html.EscapeString(code)))
+	})
+
+	content = html.EscapeString(content)
+
+	content = replaceAllSubmatchFunc(linkRegex, content, r.renderLink)
+	content = replaceAllSubmatchFunc(mentionRegex, content, r.renderMention)
+	content = replaceAllSubmatchFunc(emojiRegex, content, r.renderEmoji)
+	content = replaceAllSubmatchFunc(timestampRegex, content, r.renderTimestamp)
+
+	content = r.renderBlocks(content)
+
+	// Tokens may contain other tokens, e.g. a masked link containing an emoji
+	for placeholderRegex.MatchString(content) {
+		content = placeholderRegex.ReplaceAllStringFunc(content, func(match string) string {
+			index, _ := strconv.Atoi(strings.Trim(match, placeholderMarker))
+			return r.tokens[index]
+		})
+	}
+
+	return content
+}
+
+func (r *markdownRenderer) token(rendered string) string {
+	r.tokens = append(r.tokens, rendered)
+	return placeholderMarker + strconv.Itoa(len(r.tokens)-1) + placeholderMarker
+}
+
+// renderBlocks handles formatting that applies to whole lines: headers and block quotes
+func (r *markdownRenderer) renderBlocks(content string) string {
+	var sb strings.Builder
+	var quote []string
+
+	flushQuote := func() {
+		if len(quote) > 0 {
+			sb.WriteString("<blockquote>")
+			sb.WriteString(strings.Join(quote, "<br>"))
+			sb.WriteString("</blockquote>")
+			quote = nil
+		}
+	}
+
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "&gt; ") || line == "&gt;" {
+			quote = append(quote, renderInline(strings.TrimPrefix(strings.TrimPrefix(line, "&gt;"), " ")))
+			continue
+		}
+
+		flushQuote()
+
+		if groups := headerRegex.FindStringSubmatch(line); groups != nil {
+			level := len(groups[1])
+			sb.WriteString(fmt.Sprintf("<h%d>%s</h%d>", level, renderInline(groups[2]), level))
+			continue
+		}
+
+		sb.WriteString(renderInline(line))
+		if i < len(lines)-1 {
+			sb.WriteString("<br>")
+		}
+	}
+
+	flushQuote()
+
+	return sb.String()
+}
+
+func renderInline(content string) string {
+	for _, rule := range inlineRules {
+		content = rule.regex.ReplaceAllString(content, rule.replacement)
+	}
+
+	return content
+}
+
+func (r *markdownRenderer) renderLink(groups []string) string {
+	// Masked link
+	if groups[1] != "" {
+		return r.token(fmt.Sprintf(`<a href="%s" target="_blank" rel="noopener noreferrer">%s</a>`, groups[2], renderInline(groups[1])))
+	}
+
+	// Links wrapped in <> have their embed suppressed, but are otherwise the same as a bare link
+	url := groups[3]
+	if url == "" {
+		url = groups[4]
+	}
+
+	return r.token(fmt.Sprintf(`<a href="%s" target="_blank" rel="noopener noreferrer">%s</a>`, url, url))
+}
+
+func (r *markdownRenderer) renderMention(groups []string) string {
+	id := groups[2]
+
+	switch groups[1] {
+	case "#":
+		name := "deleted-channel"
+		if channel, ok := r.entities.Channels[id]; ok {
+			name = channel.Name
+		}
+
+		return r.token(fmt.Sprintf(`<span class="mention">#%s</span>`, html.EscapeString(name)))
+	case "@&amp;":
+		role, ok := r.entities.Roles[id]
+		if !ok {
+			return r.token(`<span class="mention">@deleted-role</span>`)
+		}
+
+		if role.Color == 0 {
+			return r.token(fmt.Sprintf(`<span class="mention">@%s</span>`, html.EscapeString(role.Name)))
+		}
+
+		return r.token(fmt.Sprintf(
+			`<span class="mention role" style="--role-colour: #%06x">@%s</span>`,
+			role.Color,
+			html.EscapeString(role.Name),
+		))
+	default:
+		name := "unknown-user"
+		if user, ok := r.entities.Users[id]; ok {
+			name = user.Username
+		}
+
+		return r.token(fmt.Sprintf(`<span class="mention">@%s</span>`, html.EscapeString(name)))
+	}
+}
+
+func (r *markdownRenderer) renderEmoji(groups []string) string {
+	extension := "png"
+	if groups[1] == "a" {
+		extension = "gif"
+	}
+
+	return r.token(fmt.Sprintf(
+		`<img class="emoji" src="https://cdn.discordapp.com/emojis/%s.%s" alt=":%s:" title=":%s:">`,
+		groups[3], extension, groups[2], groups[2],
+	))
+}
+
+func (r *markdownRenderer) renderTimestamp(groups []string) string {
+	seconds, err := strconv.ParseInt(groups[1], 10, 64)
+	if err != nil {
+		return r.token(groups[0])
+	}
+
+	style := groups[2]
+	if style == "" {
+		style = "f"
+	}
+
+	t := time.Unix(seconds, 0).UTC()
+	return r.token(fmt.Sprintf(
+		`<span class="timestamp" title="%s">%s</span>`,
+		t.Format(time.RFC3339), t.Format(timestampFormats[style]),
+	))
+}
+
+func replaceAllSubmatchFunc(regex *regexp.Regexp, s string, f func(groups []string) string) string {
+	return regex.ReplaceAllStringFunc(s, func(match string) string {
+		return f(regex.FindStringSubmatch(match))
+	})
+}
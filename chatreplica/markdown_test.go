@@ -0,0 +1,101 @@
+package chatreplica
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rxdn/gdl/objects/channel/embed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testEntities = Entities{
+	Users: map[string]User{
+		"1": {Username: "user_name"},
+	},
+	Channels: map[string]Channel{
+		"2": {Name: "general"},
+	},
+	Roles: map[string]Role{
+		"3": {Name: "Support", Color: 0xff0000},
+	},
+}
+
+func TestMarkdownEscapesHtml(t *testing.T) {
+	assert.Equal(t, "&lt;script&gt;alert(1)&lt;/script&gt;", renderMarkdown("<script>alert(1)</script>", testEntities))
+}
+
+func TestMarkdownFormatting(t *testing.T) {
+	cases := map[string]string{
+		"**bold**":           "<strong>bold</strong>",
+		"*italic*":           "<em>italic</em>",
+		"_italic_":           "<em>italic</em>",
+		"__underline__":      "<u>underline</u>",
+		"~~strike~~":         "<s>strike</s>",
+		"||spoiler||":        `<span class="spoiler">spoiler</span>`,
+		"a\nb":               "a<br>b",
+		"# Header":           "<h1>Header</h1>",
+		"> quote\n> more":    "<blockquote>quote<br>more</blockquote>",
+		"`**not bold**`":     "<code>**not bold**</code>",
+		"```go\nx := 1\n```": `<pre><code class="language-go">x := 1</code></pre>`,
+	}
+
+	for input, expected := range cases {
+		assert.Equal(t, expected, renderMarkdown(input, testEntities), input)
+	}
+}
+
+func TestMarkdownLinks(t *testing.T) {
+	assert.Equal(t,
+		`<a href="https://example.com/a_b_c" target="_blank" rel="noopener noreferrer">https://example.com/a_b_c</a>.`,
+		renderMarkdown("https://example.com/a_b_c.", testEntities),
+	)
+
+	assert.Equal(t,
+		`<a href="https://example.com" target="_blank" rel="noopener noreferrer"><strong>site</strong></a>`,
+		renderMarkdown("[**site**](https://example.com)", testEntities),
+	)
+
+	// Only http(s) links are rendered
+	assert.Equal(t, "[x](javascript:alert(1))", renderMarkdown("[x](javascript:alert(1))", testEntities))
+}
+
+func TestMarkdownMentions(t *testing.T) {
+	assert.Equal(t, `<span class="mention">@user_name</span>`, renderMarkdown("<@1>", testEntities))
+	assert.Equal(t, `<span class="mention">@user_name</span>`, renderMarkdown("<@!1>", testEntities))
+	assert.Equal(t, `<span class="mention">@unknown-user</span>`, renderMarkdown("<@4>", testEntities))
+	assert.Equal(t, `<span class="mention">#general</span>`, renderMarkdown("<#2>", testEntities))
+	assert.Equal(t, `<span class="mention role" style="--role-colour: #ff0000">@Support</span>`, renderMarkdown("<@&3>", testEntities))
+	assert.Equal(t,
+		`<img class="emoji" src="https://cdn.discordapp.com/emojis/5.gif" alt=":party_parrot:" title=":party_parrot:">`,
+		renderMarkdown("<a:party_parrot:5>", testEntities),
+	)
+	assert.Equal(t,
+		`<span class="timestamp" title="1970-01-01T00:00:00Z">1 January 1970 00:00</span>`,
+		renderMarkdown("<t:0>", testEntities),
+	)
+}
+
+func TestRenderNative(t *testing.T) {
+	payload := Payload{
+		ChannelName: "ticket-1",
+		Entities:    testEntities,
+		Messages: []Message{
+			{Id: 1, Author: 1, Time: 0, Content: "first"},
+			{Id: 2, Author: 1, Time: 1000, Content: "second", Embeds: []embed.Embed{
+				{Title: "Embed", Url: "javascript:alert(1)", Color: 0x00ff00},
+			}},
+			{Id: 3, Author: 4, Time: 2000, Content: "<img src=x>"},
+		},
+	}
+
+	html, err := RenderNative(payload)
+	require.NoError(t, err)
+
+	rendered := string(html)
+	assert.Equal(t, 2, strings.Count(rendered, `class="group"`))
+	assert.Contains(t, rendered, "Unknown User")
+	assert.Contains(t, rendered, "&lt;img src=x&gt;")
+	assert.Contains(t, rendered, "border-left-color: #00ff00")
+	assert.NotContains(t, rendered, "javascript:")
+}
@@ -0,0 +1,93 @@
+package chatreplica
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/rxdn/gdl/objects/channel/embed"
+)
+
+var (
+	channelMentionRegex = regexp.MustCompile(`<#(\d+)>`)
+	roleMentionRegex    = regexp.MustCompile(`<@&(\d+)>`)
+)
+
+// UnresolvedMentions returns the IDs of the channels and roles that are mentioned in the payload's messages, including
+// their embeds, but are missing from its entities. Each ID is returned at most once.
+func (p Payload) UnresolvedMentions() (channelIds, roleIds []uint64) {
+	seenChannels := make(map[string]bool)
+	seenRoles := make(map[string]bool)
+
+	for _, msg := range p.Messages {
+		for _, text := range mentionableText(msg) {
+			for _, match := range channelMentionRegex.FindAllStringSubmatch(text, -1) {
+				if _, ok := p.Entities.Channels[match[1]]; ok || seenChannels[match[1]] {
+					continue
+				}
+
+				if id, err := strconv.ParseUint(match[1], 10, 64); err == nil {
+					seenChannels[match[1]] = true
+					channelIds = append(channelIds, id)
+				}
+			}
+
+			for _, match := range roleMentionRegex.FindAllStringSubmatch(text, -1) {
+				if _, ok := p.Entities.Roles[match[1]]; ok || seenRoles[match[1]] {
+					continue
+				}
+
+				if id, err := strconv.ParseUint(match[1], 10, 64); err == nil {
+					seenRoles[match[1]] = true
+					roleIds = append(roleIds, id)
+				}
+			}
+		}
+	}
+
+	return
+}
+
+// AddChannel adds a channel to the payload's entities, so mentions of it are shown by name
+func (p *Payload) AddChannel(id uint64, name string) {
+	if p.Entities.Channels == nil {
+		p.Entities.Channels = make(map[string]Channel)
+	}
+
+	p.Entities.Channels[strconv.FormatUint(id, 10)] = Channel{
+		Name: name,
+	}
+}
+
+// AddRole adds a role to the payload's entities, so mentions of it are shown by name, in the role's colour
+func (p *Payload) AddRole(id uint64, name string, colour int) {
+	if p.Entities.Roles == nil {
+		p.Entities.Roles = make(map[string]Role)
+	}
+
+	p.Entities.Roles[strconv.FormatUint(id, 10)] = Role{
+		Name:  name,
+		Color: colour,
+	}
+}
+
+func mentionableText(msg Message) []string {
+	text := []string{msg.Content}
+	for _, e := range msg.Embeds {
+		text = append(text, embedText(e)...)
+	}
+
+	return text
+}
+
+func embedText(e embed.Embed) []string {
+	text := []string{e.Title, e.Description}
+	for _, field := range e.Fields {
+		text = append(text, field.Name, field.Value)
+	}
+
+	if e.Footer != nil {
+		text = append(text, e.Footer.Text)
+	}
+
+	return text
+}
@@ -0,0 +1,39 @@
+package chatreplica
+
+import (
+	"testing"
+
+	"github.com/rxdn/gdl/objects/channel/embed"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnresolvedMentions(t *testing.T) {
+	payload := Payload{
+		Entities: testEntities,
+		Messages: []Message{
+			{Content: "see <#2> and <#4>, ask <@&3> or <@&5>"},
+			{Content: "<#4> again", Embeds: []embed.Embed{
+				{Description: "<@&6>", Fields: []*embed.EmbedField{{Value: "<#7>"}}},
+			}},
+		},
+	}
+
+	channelIds, roleIds := payload.UnresolvedMentions()
+	assert.Equal(t, []uint64{4, 7}, channelIds)
+	assert.Equal(t, []uint64{5, 6}, roleIds)
+}
+
+func TestAddEntitiesResolvesMentions(t *testing.T) {
+	payload := Payload{
+		Messages: []Message{{Content: "<#4> <@&5>"}},
+	}
+
+	payload.AddChannel(4, "support")
+	payload.AddRole(5, "Staff", 0x00ff00)
+
+	channelIds, roleIds := payload.UnresolvedMentions()
+	assert.Empty(t, channelIds)
+	assert.Empty(t, roleIds)
+
+	assert.Equal(t, `<span class="mention">#support</span> <span class="mention role" style="--role-colour: #00ff00">@Staff</span>`, renderMarkdown(payload.Messages[0].Content, payload.Entities))
+}
@@ -0,0 +1,136 @@
+package chatreplica
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rxdn/gdl/objects/channel"
+	"github.com/rxdn/gdl/objects/channel/embed"
+)
+
+//go:embed transcript.html
+var transcriptTemplateSource string
+
+var transcriptTemplate = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"markdown": func(content string, entities Entities) template.HTML {
+		return template.HTML(renderMarkdown(content, entities))
+	},
+	"colour":    func(colour int) template.CSS { return template.CSS(fmt.Sprintf("#%06x", colour)) },
+	"timestamp": func(t time.Time) string { return t.UTC().Format("02/01/2006 15:04") },
+	"filesize":  formatFileSize,
+	"isImage":   isImageAttachment,
+	"redacted":  IsRedactedAttachment,
+}).Parse(transcriptTemplateSource))
+
+// Consecutive messages from the same author are shown together, under a single header, as in the Discord client
+const messageGroupWindow = 7 * time.Minute
+
+type (
+	nativePayload struct {
+		ChannelName string
+		Entities    Entities
+		Groups      []messageGroup
+	}
+
+	messageGroup struct {
+		Author   User
+		AuthorId uint64
+		Time     time.Time
+		Messages []nativeMessage
+	}
+
+	nativeMessage struct {
+		Id          uint64
+		Time        time.Time
+		Redacted    bool
+		Content     string
+		Embeds      []embed.Embed
+		Attachments []channel.Attachment
+	}
+)
+
+// RenderNative renders a transcript to a self-contained HTML page, without the render service. Images, such as avatars
+// and attachments, are still loaded from Discord's CDN.
+func RenderNative(payload Payload) ([]byte, error) {
+	data := nativePayload{
+		ChannelName: payload.ChannelName,
+		Entities:    payload.Entities,
+	}
+
+	for _, msg := range payload.Messages {
+		// Message.Time is in milliseconds
+		sentAt := time.UnixMilli(msg.Time)
+
+		wrapped := nativeMessage{
+			Id:          msg.Id,
+			Time:        sentAt,
+			Redacted:    IsRedactedMessage(msg),
+			Content:     msg.Content,
+			Embeds:      msg.Embeds,
+			Attachments: msg.Attachments,
+		}
+
+		if len(data.Groups) > 0 {
+			group := &data.Groups[len(data.Groups)-1]
+			last := group.Messages[len(group.Messages)-1]
+
+			if group.AuthorId == msg.Author && sentAt.Sub(last.Time) < messageGroupWindow {
+				group.Messages = append(group.Messages, wrapped)
+				continue
+			}
+		}
+
+		author, ok := payload.Entities.Users[strconv.FormatUint(msg.Author, 10)]
+		if !ok {
+			author = User{
+				Username: "Unknown User",
+			}
+		}
+
+		data.Groups = append(data.Groups, messageGroup{
+			Author:   author,
+			AuthorId: msg.Author,
+			Time:     sentAt,
+			Messages: []nativeMessage{wrapped},
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := transcriptTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func formatFileSize(size int) string {
+	units := []string{"B", "KB", "MB", "GB"}
+
+	value := float64(size)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+
+	if unit == 0 {
+		return fmt.Sprintf("%d %s", size, units[unit])
+	}
+
+	return fmt.Sprintf("%.2f %s", value, units[unit])
+}
+
+func isImageAttachment(attachment channel.Attachment) bool {
+	switch strings.ToLower(path.Ext(attachment.Filename)) {
+	case ".png", ".jpg", ".jpeg", ".gif", ".webp":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,92 @@
+package chatreplica
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// StaffNote is an internal note left on a ticket by staff. Notes are not part of the transcript itself, but may be
+// appended to an export for staff.
+type StaffNote struct {
+	Author    uint64
+	Content   string
+	CreatedAt time.Time
+}
+
+type jsonlStaffNote struct {
+	Type      string    `json:"type"`
+	AuthorId  uint64    `json:"author_id,string"`
+	Author    string    `json:"author"`
+	Timestamp time.Time `json:"timestamp"`
+	Content   string    `json:"content"`
+}
+
+// AddUser adds a user to the payload's entities, so that they are shown by name
+func (p *Payload) AddUser(id uint64, username, avatar string) {
+	if p.Entities.Users == nil {
+		p.Entities.Users = make(map[string]User)
+	}
+
+	p.Entities.Users[strconv.FormatUint(id, 10)] = User{
+		Avatar:   avatar,
+		Username: username,
+	}
+}
+
+// HasUser returns whether the user is present in the payload's entities
+func (p Payload) HasUser(id uint64) bool {
+	_, ok := p.Entities.Users[strconv.FormatUint(id, 10)]
+	return ok
+}
+
+// ExportStaffNotes formats staff notes as a section to be appended to an export of the payload in the same format.
+// Returns nil if there are no notes.
+func ExportStaffNotes(payload Payload, notes []StaffNote, format ExportFormat) ([]byte, error) {
+	if len(notes) == 0 {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case ExportFormatText:
+		buf.WriteString("\n--- Staff notes (internal) ---\n")
+
+		for _, note := range notes {
+			buf.WriteString(fmt.Sprintf("[%s] %s: %s\n", formatNoteTime(note), authorName(payload.Entities, note.Author), resolveMentions(note.Content, payload.Entities)))
+		}
+	case ExportFormatMarkdown:
+		buf.WriteString("\n## Staff notes\n\n*Internal: not visible to the ticket opener*\n")
+
+		for _, note := range notes {
+			buf.WriteString(fmt.Sprintf("\n**%s** — %s\n", escapeMarkdown(authorName(payload.Entities, note.Author)), formatNoteTime(note)))
+			buf.WriteString(resolveMentions(note.Content, payload.Entities))
+			buf.WriteString("\n")
+		}
+	case ExportFormatJsonl:
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+
+		for _, note := range notes {
+			if err := encoder.Encode(jsonlStaffNote{
+				Type:      "staff_note",
+				AuthorId:  note.Author,
+				Author:    authorName(payload.Entities, note.Author),
+				Timestamp: note.CreatedAt.UTC(),
+				Content:   resolveMentions(note.Content, payload.Entities),
+			}); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown export format %s", format)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func formatNoteTime(note StaffNote) string {
+	return note.CreatedAt.UTC().Format(exportTimeFormat)
+}
@@ -0,0 +1,51 @@
+package chatreplica
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testNotes = []StaffNote{
+	{Author: 1, Content: "escalated to <@&3>", CreatedAt: time.Unix(60, 0)},
+	{Author: 4, Content: "refund issued", CreatedAt: time.Unix(120, 0)},
+}
+
+func TestExportStaffNotesText(t *testing.T) {
+	exported, err := ExportStaffNotes(testPayload, testNotes, ExportFormatText)
+	require.NoError(t, err)
+
+	assert.Equal(t, `
+--- Staff notes (internal) ---
+[1970-01-01 00:01:00 UTC] user_name: escalated to @Support
+[1970-01-01 00:02:00 UTC] Unknown User: refund issued
+`, string(exported))
+}
+
+func TestExportStaffNotesJsonl(t *testing.T) {
+	payload := testPayload
+	payload.Entities.Users = map[string]User{}
+	payload.AddUser(4, "agent", "")
+
+	exported, err := ExportStaffNotes(payload, testNotes, ExportFormatJsonl)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(string(exported), "\n"), "\n")
+	require.Len(t, lines, 2)
+
+	var note jsonlStaffNote
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &note))
+	assert.Equal(t, "staff_note", note.Type)
+	assert.Equal(t, "agent", note.Author)
+	assert.Equal(t, "refund issued", note.Content)
+}
+
+func TestExportStaffNotesEmpty(t *testing.T) {
+	exported, err := ExportStaffNotes(testPayload, nil, ExportFormatMarkdown)
+	require.NoError(t, err)
+	assert.Nil(t, exported)
+}
@@ -19,7 +19,7 @@ var client = &http.Client{
 	Timeout: time.Second * 3,
 }
 
-func Render(payload Payload) ([]byte, error) {
+func renderWithService(payload Payload) ([]byte, error) {
 	encoded, err := json.Marshal(payload)
 	if err != nil {
 		return nil, err
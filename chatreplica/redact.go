@@ -0,0 +1,81 @@
+package chatreplica
+
+import (
+	"errors"
+
+	v2 "github.com/TicketsBot/logarchiver/pkg/model/v2"
+	"github.com/rxdn/gdl/objects/channel"
+)
+
+// RedactedPlaceholder replaces the content of redacted messages, and the filename of redacted attachments, in the
+// stored transcript. The original is not kept anywhere.
+const RedactedPlaceholder = "[redacted by staff]"
+
+var (
+	ErrMessageNotFound    = errors.New("message not found")
+	ErrAttachmentNotFound = errors.New("attachment not found")
+	ErrAlreadyRedacted    = errors.New("already redacted")
+)
+
+// RedactMessage replaces a message's content with the placeholder, removing its embeds and attachments
+func RedactMessage(transcript *v2.Transcript, messageId uint64) error {
+	msg := findMessage(transcript, messageId)
+	if msg == nil {
+		return ErrMessageNotFound
+	}
+
+	if msg.Content == RedactedPlaceholder && len(msg.Embeds) == 0 && len(msg.Attachments) == 0 {
+		return ErrAlreadyRedacted
+	}
+
+	msg.Content = RedactedPlaceholder
+	msg.Embeds = nil
+	msg.Attachments = nil
+
+	return nil
+}
+
+// RedactAttachment replaces a single attachment with the placeholder, leaving the rest of the message intact
+func RedactAttachment(transcript *v2.Transcript, messageId, attachmentId uint64) error {
+	msg := findMessage(transcript, messageId)
+	if msg == nil {
+		return ErrMessageNotFound
+	}
+
+	for i, attachment := range msg.Attachments {
+		if attachment.Id != attachmentId {
+			continue
+		}
+
+		if IsRedactedAttachment(attachment) {
+			return ErrAlreadyRedacted
+		}
+
+		msg.Attachments[i] = channel.Attachment{
+			Id:       attachment.Id,
+			Filename: RedactedPlaceholder,
+		}
+
+		return nil
+	}
+
+	return ErrAttachmentNotFound
+}
+
+func IsRedactedMessage(msg Message) bool {
+	return msg.Content == RedactedPlaceholder && len(msg.Embeds) == 0 && len(msg.Attachments) == 0
+}
+
+func IsRedactedAttachment(attachment channel.Attachment) bool {
+	return attachment.Filename == RedactedPlaceholder && attachment.Url == ""
+}
+
+func findMessage(transcript *v2.Transcript, messageId uint64) *v2.Message {
+	for i := range transcript.Messages {
+		if transcript.Messages[i].Id == messageId {
+			return &transcript.Messages[i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package chatreplica
+
+import (
+	"testing"
+
+	v2 "github.com/TicketsBot/logarchiver/pkg/model/v2"
+	"github.com/rxdn/gdl/objects/channel"
+	"github.com/rxdn/gdl/objects/channel/embed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRedactTranscript() v2.Transcript {
+	return v2.Transcript{
+		Messages: []v2.Message{
+			{Id: 1, Content: "my password is hunter2", Embeds: []embed.Embed{{Title: "embed"}}},
+			{Id: 2, Content: "card attached", Attachments: []channel.Attachment{
+				{Id: 10, Filename: "card.png", Url: "https://cdn.discordapp.com/card.png"},
+				{Id: 11, Filename: "other.png", Url: "https://cdn.discordapp.com/other.png"},
+			}},
+		},
+	}
+}
+
+func TestRedactMessage(t *testing.T) {
+	transcript := newRedactTranscript()
+
+	require.NoError(t, RedactMessage(&transcript, 1))
+	assert.Equal(t, RedactedPlaceholder, transcript.Messages[0].Content)
+	assert.Empty(t, transcript.Messages[0].Embeds)
+	assert.Equal(t, "card attached", transcript.Messages[1].Content)
+
+	assert.ErrorIs(t, RedactMessage(&transcript, 1), ErrAlreadyRedacted)
+	assert.ErrorIs(t, RedactMessage(&transcript, 3), ErrMessageNotFound)
+
+	assert.True(t, IsRedactedMessage(MessagesFromTranscript(transcript.Messages)[0]))
+}
+
+func TestRedactAttachment(t *testing.T) {
+	transcript := newRedactTranscript()
+
+	require.NoError(t, RedactAttachment(&transcript, 2, 10))
+
+	attachments := transcript.Messages[1].Attachments
+	require.Len(t, attachments, 2)
+	assert.True(t, IsRedactedAttachment(attachments[0]))
+	assert.False(t, IsRedactedAttachment(attachments[1]))
+	assert.Equal(t, "card attached", transcript.Messages[1].Content)
+
+	assert.ErrorIs(t, RedactAttachment(&transcript, 2, 10), ErrAlreadyRedacted)
+	assert.ErrorIs(t, RedactAttachment(&transcript, 2, 12), ErrAttachmentNotFound)
+	assert.ErrorIs(t, RedactAttachment(&transcript, 3, 10), ErrMessageNotFound)
+}
+
+func TestRenderNativeRedacted(t *testing.T) {
+	transcript := newRedactTranscript()
+	require.NoError(t, RedactMessage(&transcript, 1))
+	require.NoError(t, RedactAttachment(&transcript, 2, 10))
+
+	html, err := RenderNative(FromTranscript(transcript, 1))
+	require.NoError(t, err)
+
+	assert.NotContains(t, string(html), "hunter2")
+	assert.NotContains(t, string(html), "card.png")
+	assert.Contains(t, string(html), `<div class="content redacted">[redacted by staff]</div>`)
+	assert.Contains(t, string(html), `<div class="attachment redacted">[redacted by staff]</div>`)
+}
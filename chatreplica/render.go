@@ -0,0 +1,74 @@
+package chatreplica
+
+import (
+	"fmt"
+
+	"github.com/TicketsBot-cloud/dashboard/config"
+	"github.com/TicketsBot-cloud/dashboard/log"
+	"go.uber.org/zap"
+)
+
+const (
+	RendererService = "service"
+	RendererNative  = "native"
+)
+
+// NativeRendererVersion must be incremented whenever the output of the native renderer changes, so that renders cached
+// by an older version are no longer served
+const NativeRendererVersion = 1
+
+// Render renders a transcript to HTML. The render service is used if one is configured, falling back to the native
+// renderer if it is unavailable, so that transcripts can still be read while the service is down.
+func Render(payload Payload) ([]byte, error) {
+	html, _, err := RenderVersioned(payload)
+	return html, err
+}
+
+// RenderVersioned renders a transcript to HTML as Render does, also returning the version of the renderer used. The
+// version is empty if the native renderer was only used as a fallback, as its output should not be cached in place of
+// a render from the service once it has recovered.
+func RenderVersioned(payload Payload) ([]byte, string, error) {
+	if !useRenderService() {
+		html, err := RenderNative(payload)
+		return html, nativeRendererVersion(), err
+	}
+
+	html, err := renderWithService(payload)
+	if err != nil {
+		log.Logger.Warn("Render service failed, falling back to native renderer", zap.Error(err))
+
+		html, err := RenderNative(payload)
+		return html, "", err
+	}
+
+	return html, RendererService, nil
+}
+
+// RenderVersions returns the versions of the renderers whose cached output may be served. Cached output from the native
+// renderer is not served if the render service is configured, so that it is retried.
+func RenderVersions() []string {
+	if useRenderService() {
+		return []string{RendererService}
+	} else {
+		return []string{nativeRendererVersion()}
+	}
+}
+
+// ValidateRenderer returns an error if the configured transcript renderer is not one of the known renderers, so that a
+// typo is caught at startup, rather than silently using the render service.
+func ValidateRenderer(renderer string) error {
+	switch renderer {
+	case RendererService, RendererNative:
+		return nil
+	default:
+		return fmt.Errorf("unknown transcript renderer %q, must be %q or %q", renderer, RendererService, RendererNative)
+	}
+}
+
+func useRenderService() bool {
+	return config.Conf.Bot.TranscriptRenderer != RendererNative && config.Conf.Bot.RenderServiceUrl != ""
+}
+
+func nativeRendererVersion() string {
+	return fmt.Sprintf("%s-%d", RendererNative, NativeRendererVersion)
+}
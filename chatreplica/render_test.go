@@ -0,0 +1,40 @@
+package chatreplica
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TicketsBot-cloud/dashboard/config"
+	"github.com/TicketsBot-cloud/dashboard/log"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestValidateRenderer(t *testing.T) {
+	assert.NoError(t, ValidateRenderer(RendererService))
+	assert.NoError(t, ValidateRenderer(RendererNative))
+	assert.Error(t, ValidateRenderer("natve"))
+	assert.Error(t, ValidateRenderer(""))
+}
+
+func TestRenderVersionedFallbackNotVersioned(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	log.Logger = zap.NewNop()
+	config.Conf.Bot.TranscriptRenderer = RendererService
+	config.Conf.Bot.RenderServiceUrl = server.URL
+	defer func() {
+		config.Conf.Bot.TranscriptRenderer = ""
+		config.Conf.Bot.RenderServiceUrl = ""
+	}()
+
+	html, version, err := RenderVersioned(Payload{})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, html)
+	assert.Empty(t, version)
+	assert.Equal(t, []string{RendererService}, RenderVersions())
+}
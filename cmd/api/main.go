@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/pprof"
+	"time"
 
 	"github.com/TicketsBot-cloud/archiverclient"
 	"github.com/TicketsBot-cloud/common/chatrelay"
@@ -13,6 +16,7 @@ import (
 	"github.com/TicketsBot-cloud/common/secureproxy"
 	app "github.com/TicketsBot-cloud/dashboard/app/http"
 	"github.com/TicketsBot-cloud/dashboard/app/http/endpoints/api/ticket/livechat"
+	"github.com/TicketsBot-cloud/dashboard/chatreplica"
 	"github.com/TicketsBot-cloud/dashboard/config"
 	"github.com/TicketsBot-cloud/dashboard/database"
 	"github.com/TicketsBot-cloud/dashboard/log"
@@ -39,6 +43,8 @@ func main() {
 	utils.Must(err)
 	config.Conf = cfg
 
+	utils.Must(chatreplica.ValidateRenderer(config.Conf.Bot.TranscriptRenderer))
+
 	if config.Conf.SentryDsn != nil {
 		sentryOpts := sentry.ClientOptions{
 			Dsn:              *config.Conf.SentryDsn,
@@ -87,7 +93,8 @@ func main() {
 	s3.ConnectS3(config.Conf.S3Import.Endpoint, config.Conf.S3Import.AccessKey, config.Conf.S3Import.SecretKey)
 
 	logger.Info("Initialising microservice clients")
-	utils.ArchiverClient = archiverclient.NewArchiverClient(archiverclient.NewProxyRetriever(config.Conf.Bot.ObjectStore), []byte(config.Conf.Bot.AesKey))
+	utils.ArchiverRetriever = archiverclient.NewProxyRetriever(config.Conf.Bot.ObjectStore)
+	utils.ArchiverClient = archiverclient.NewArchiverClient(utils.ArchiverRetriever, []byte(config.Conf.Bot.AesKey))
 	utils.SecureProxyClient = secureproxy.NewSecureProxy(config.Conf.SecureProxyUrl)
 
 	utils.LoadEmoji()
@@ -105,6 +112,16 @@ func main() {
 	go socketManager.Run()
 
 	go ListenChat(redis.Client, socketManager)
+	go ListenTicketEvents(redis.Client, socketManager)
+	go ListenPresence(redis.Client, socketManager)
+	go ListenPermissionRevalidations(redis.Client, socketManager)
+	go ListenTicketNotes(redis.Client, socketManager)
+	go IndexClosedTranscripts(redis.Client)
+	go PurgeExpiredTranscripts(redis.Client)
+
+	if config.Conf.Bot.ChatRelayPatches {
+		go ListenMessagePatches(redis.Client, socketManager)
+	}
 
 	if !config.Conf.Debug {
 		rpc.PremiumClient = premium.NewPremiumLookupClient(
@@ -126,10 +143,263 @@ func ListenChat(client *redis.RedisClient, sm *livechat.SocketManager) {
 	go chatrelay.Listen(client.Client, ch)
 
 	for event := range ch {
+		// Must be stored before broadcasting, so that a resuming client either replays the message or receives it live
+		if err := client.AppendLiveChatHistory(redis.DefaultContext(), event); err != nil {
+			log.Logger.Warn("Failed to store live-chat history", zap.Error(err))
+		}
+
 		sm.BroadcastMessage(event)
 	}
 }
 
+func ListenMessagePatches(client *redis.RedisClient, sm *livechat.SocketManager) {
+	updates := make(chan chatrelay.MessageData)
+	go client.ListenMessageUpdates(updates)
+
+	deletes := make(chan redis.MessageDeleteData)
+	go client.ListenMessageDeletes(deletes)
+
+	for {
+		// As with new messages, the history must be patched before broadcasting, so that a resuming client either
+		// replays the patched history or receives the patch live
+		select {
+		case event := <-updates:
+			if err := client.UpdateLiveChatHistory(redis.DefaultContext(), event); err != nil {
+				log.Logger.Warn("Failed to update live-chat history", zap.Error(err))
+			}
+
+			sm.BroadcastMessageUpdate(event)
+		case event := <-deletes:
+			if err := client.DeleteLiveChatHistory(redis.DefaultContext(), event.Ticket.GuildId, event.Ticket.Id, event.MessageId); err != nil {
+				log.Logger.Warn("Failed to update live-chat history", zap.Error(err))
+			}
+
+			sm.BroadcastMessageDelete(event)
+		}
+	}
+}
+
+func ListenTicketEvents(client *redis.RedisClient, sm *livechat.SocketManager) {
+	ch := make(chan redis.TicketEvent)
+	go client.ListenTicketEvents(ch)
+
+	for event := range ch {
+		sm.BroadcastTicketEvent(event)
+	}
+}
+
+func ListenPresence(client *redis.RedisClient, sm *livechat.SocketManager) {
+	ch := make(chan redis.PresenceEvent)
+	go client.ListenPresenceEvents(ch)
+
+	for event := range ch {
+		sm.BroadcastPresenceEvent(event)
+	}
+}
+
+func ListenPermissionRevalidations(client *redis.RedisClient, sm *livechat.SocketManager) {
+	ch := make(chan redis.PermissionRevalidation)
+	go client.ListenPermissionRevalidations(ch)
+
+	for data := range ch {
+		sm.RevalidatePermissions(data)
+	}
+}
+
+func ListenTicketNotes(client *redis.RedisClient, sm *livechat.SocketManager) {
+	ch := make(chan redis.TicketNoteEvent)
+	go client.ListenTicketNoteEvents(ch)
+
+	for event := range ch {
+		sm.BroadcastTicketNoteEvent(event)
+	}
+}
+
+// The transcript may not have been uploaded by the time the ticket closed event is received, so retry for a while
+const (
+	transcriptIndexAttempts   = 3
+	transcriptIndexRetryDelay = 15 * time.Second
+)
+
+// IndexClosedTranscripts indexes transcripts as soon as their ticket is closed from the dashboard. The worker does not
+// publish ticket events, so tickets closed from Discord are picked up by a periodic sweep instead.
+func IndexClosedTranscripts(client *redis.RedisClient) {
+	go sweepUnindexedTranscripts(client)
+
+	ch := make(chan redis.TicketEvent)
+	go client.ListenTicketEvents(ch)
+
+	for event := range ch {
+		if event.Type != redis.TicketEventClosed {
+			continue
+		}
+
+		go indexTranscript(client, event.GuildId, event.TicketId)
+	}
+}
+
+func indexTranscript(client *redis.RedisClient, guildId uint64, ticketId int) {
+	logger := log.Logger.With(zap.Uint64("guild_id", guildId), zap.Int("ticket_id", ticketId))
+
+	ok, err := client.TakeTranscriptIndexLock(redis.DefaultContext(), guildId, ticketId)
+	if err != nil {
+		logger.Error("Failed to take transcript index lock", zap.Error(err))
+		return
+	}
+
+	if !ok {
+		return // Being indexed by another replica
+	}
+
+	for attempt := 1; attempt <= transcriptIndexAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		err = utils.IndexTranscript(ctx, guildId, ticketId)
+		cancel()
+
+		if err == nil {
+			return
+		}
+
+		if !errors.Is(err, archiverclient.ErrNotFound) || attempt == transcriptIndexAttempts {
+			break
+		}
+
+		time.Sleep(transcriptIndexRetryDelay)
+	}
+
+	logger.Warn("Failed to index transcript", zap.Error(err))
+}
+
+const (
+	transcriptIndexSweepInterval  = 10 * time.Minute
+	transcriptIndexSweepBatchSize = 25
+)
+
+func sweepUnindexedTranscripts(client *redis.RedisClient) {
+	ticker := time.NewTicker(transcriptIndexSweepInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ok, err := client.TakeTranscriptIndexSweepLock(redis.DefaultContext(), transcriptIndexSweepInterval)
+		if err != nil {
+			log.Logger.Error("Failed to take transcript index sweep lock", zap.Error(err))
+			continue
+		}
+
+		if !ok {
+			continue // Another replica is sweeping this interval
+		}
+
+		indexUnindexedTranscripts()
+	}
+}
+
+// indexUnindexedTranscripts indexes a batch of new transcripts for each guild that has enabled search
+func indexUnindexedTranscripts() {
+	ctx, cancel := context.WithTimeout(context.Background(), transcriptIndexSweepInterval)
+	defer cancel()
+
+	guildIds, err := database.Dashboard.TranscriptSearch.GetEnabledGuilds(ctx)
+	if err != nil {
+		log.Logger.Error("Failed to fetch guilds using transcript search", zap.Error(err))
+		return
+	}
+
+	var total int
+	for _, guildId := range guildIds {
+		indexed, _, _, err := utils.BackfillTranscripts(ctx, guildId, transcriptIndexSweepBatchSize)
+		total += indexed
+
+		if err != nil && !errors.Is(err, database.ErrTranscriptSearchDisabled) {
+			log.Logger.Error("Failed to index transcripts", zap.Error(err), zap.Uint64("guild_id", guildId))
+
+			if ctx.Err() != nil {
+				return
+			}
+		}
+	}
+
+	if total > 0 {
+		log.Logger.Info("Indexed transcripts", zap.Int("indexed", total))
+	}
+}
+
+const (
+	transcriptRetentionInterval  = time.Hour
+	transcriptRetentionBatchSize = 500
+)
+
+// PurgeExpiredTranscripts periodically deletes transcripts that have outlived their guild's retention period, and
+// transcript archives that have outlived utils.TranscriptArchiveExpiry
+func PurgeExpiredTranscripts(client *redis.RedisClient) {
+	ticker := time.NewTicker(transcriptRetentionInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ok, err := client.TakeTranscriptRetentionLock(redis.DefaultContext(), transcriptRetentionInterval)
+		if err != nil {
+			log.Logger.Error("Failed to take transcript retention lock", zap.Error(err))
+			continue
+		}
+
+		if !ok {
+			continue // Another replica is purging this interval
+		}
+
+		purgeExpiredTranscripts()
+		deleteExpiredTranscriptArchives()
+	}
+}
+
+func purgeExpiredTranscripts() {
+	ctx, cancel := context.WithTimeout(context.Background(), transcriptRetentionInterval)
+	defer cancel()
+
+	// Keep purging full batches, so that a guild shortening its retention period does not take days to catch up
+	var total int
+	for {
+		purged, err := utils.PurgeExpiredTranscripts(ctx, transcriptRetentionBatchSize)
+		total += purged
+
+		if err != nil {
+			log.Logger.Error("Failed to purge expired transcripts", zap.Error(err), zap.Int("purged", total))
+			return
+		}
+
+		if purged < transcriptRetentionBatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Logger.Info("Purged expired transcripts", zap.Int("purged", total))
+	}
+}
+
+func deleteExpiredTranscriptArchives() {
+	ctx, cancel := context.WithTimeout(context.Background(), transcriptRetentionInterval)
+	defer cancel()
+
+	var total int
+	for {
+		deleted, err := utils.DeleteExpiredTranscriptArchives(ctx, transcriptRetentionBatchSize)
+		total += deleted
+
+		if err != nil {
+			log.Logger.Error("Failed to delete expired transcript archives", zap.Error(err), zap.Int("deleted", total))
+			return
+		}
+
+		if deleted < transcriptRetentionBatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Logger.Info("Deleted expired transcript archives", zap.Int("deleted", total))
+	}
+}
+
 func startPprof() {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/debug/pprof/", pprof.Index)
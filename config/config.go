@@ -43,6 +43,9 @@ type Config struct {
 		AesKey                               string `env:"LOG_AES_KEY" toml:"aes-key"`
 		ProxyUrl                             string `env:"DISCORD_PROXY_URL" toml:"discord-proxy-url"`
 		RenderServiceUrl                     string `env:"RENDER_SERVICE_URL" toml:"render-service-url"`
+		TranscriptRenderer                   string `env:"TRANSCRIPT_RENDERER" envDefault:"service" toml:"transcript-renderer"`
+		TicketOpenRelay                      bool   `env:"TICKET_OPEN_RELAY" toml:"ticket-open-relay"`   // Only enable once the worker consumes tickets:open
+		ChatRelayPatches                     bool   `env:"CHAT_RELAY_PATCHES" toml:"chat-relay-patches"` // Only enable once the worker publishes message edits and deletions
 		ImageProxySecret                     string `env:"IMAGE_PROXY_SECRET" toml:"image-proxy-secret"`
 		PublicIntegrationRequestWebhookId    uint64 `env:"PUBLIC_INTEGRATION_REQUEST_WEBHOOK_ID" toml:"public-integration-request-webhook-id"`
 		PublicIntegrationRequestWebhookToken string `env:"PUBLIC_INTEGRATION_REQUEST_WEBHOOK_TOKEN" toml:"public-integration-request-webhook-token"`
@@ -63,6 +66,7 @@ type Config struct {
 		SecretKey        string `env:"SECRET_KEY,required"`
 		TranscriptBucket string `env:"TRANSCRIPT_BUCKET,required"`
 		DataBucket       string `env:"DATA_BUCKET,required"`
+		ArchiveBucket    string `env:"ARCHIVE_BUCKET"` // Bulk transcript downloads are disabled if unset
 	} `envPrefix:"S3_IMPORT_"`
 }
 
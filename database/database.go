@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/TicketsBot-cloud/dashboard/config"
+	"github.com/TicketsBot-cloud/dashboard/log"
 	"github.com/TicketsBot-cloud/database"
 	"github.com/jackc/pgconn"
 	"github.com/jackc/pgconn/stmtcache"
@@ -11,10 +12,14 @@ import (
 	"github.com/jackc/pgx/v4/log/logrusadapter"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/sirupsen/logrus"
+	"go.uber.org/zap"
 )
 
 var Client *database.Database
 
+// Dashboard holds the tables that only the dashboard uses, and so are not part of the shared database module
+var Dashboard *DashboardTables
+
 func ConnectToDatabase() {
 	config, err := pgxpool.ParseConfig(config.Conf.Database.Uri)
 	if err != nil {
@@ -38,4 +43,11 @@ func ConnectToDatabase() {
 	}
 
 	Client = database.NewDatabase(pool)
+
+	Dashboard = newDashboardTables(pool)
+
+	// Only the features using these tables are unavailable if they cannot be created, so the dashboard still starts
+	if err := Dashboard.CreateTables(context.Background(), pool); err != nil {
+		log.Logger.Error("Failed to create dashboard tables", zap.Error(err))
+	}
 }
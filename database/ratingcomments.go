@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// RatingCommentTable stores the optional comment left alongside a ServiceRatings score from the dashboard
+type RatingCommentTable struct {
+	*pgxpool.Pool
+}
+
+func newRatingCommentTable(db *pgxpool.Pool) *RatingCommentTable {
+	return &RatingCommentTable{
+		db,
+	}
+}
+
+func (r RatingCommentTable) Schema() string {
+	return `
+CREATE TABLE IF NOT EXISTS rating_comments(
+	"guild_id" int8 NOT NULL,
+	"ticket_id" int4 NOT NULL,
+	"comment" TEXT NOT NULL,
+	PRIMARY KEY("guild_id", "ticket_id")
+);
+`
+}
+
+func (r *RatingCommentTable) Get(ctx context.Context, guildId uint64, ticketId int) (comment string, ok bool, e error) {
+	query := `SELECT "comment" FROM rating_comments WHERE "guild_id" = $1 AND "ticket_id" = $2;`
+
+	if err := r.QueryRow(ctx, query, guildId, ticketId).Scan(&comment); err != nil {
+		if err == pgx.ErrNoRows {
+			return "", false, nil
+		} else {
+			return "", false, err
+		}
+	}
+
+	return comment, true, nil
+}
+
+func (r *RatingCommentTable) GetMulti(ctx context.Context, guildId uint64, ticketIds []int) (map[int]string, error) {
+	query := `
+SELECT "ticket_id", "comment"
+FROM rating_comments
+WHERE "guild_id" = $1 AND "ticket_id" = ANY($2);
+`
+
+	idArray := &pgtype.Int4Array{}
+	if err := idArray.Set(ticketIds); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.Query(ctx, query, guildId, idArray)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	comments := make(map[int]string)
+	for rows.Next() {
+		var ticketId int
+		var comment string
+		if err := rows.Scan(&ticketId, &comment); err != nil {
+			return nil, err
+		}
+
+		comments[ticketId] = comment
+	}
+
+	return comments, rows.Err()
+}
+
+func (r *RatingCommentTable) Set(ctx context.Context, guildId uint64, ticketId int, comment string) (err error) {
+	query := `
+INSERT INTO rating_comments("guild_id", "ticket_id", "comment")
+VALUES($1, $2, $3)
+ON CONFLICT("guild_id", "ticket_id") DO UPDATE SET "comment" = $3;`
+
+	_, err = r.Exec(ctx, query, guildId, ticketId, comment)
+	return
+}
+
+func (r *RatingCommentTable) Delete(ctx context.Context, guildId uint64, ticketId int) (err error) {
+	_, err = r.Exec(ctx, `DELETE FROM rating_comments WHERE "guild_id" = $1 AND "ticket_id" = $2;`, guildId, ticketId)
+	return
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// DefaultRatingWindow is how long after a ticket is closed its opener may rate it, if the guild has not configured it
+const DefaultRatingWindow = 7 * 24 * time.Hour
+
+type RatingWindowTable struct {
+	*pgxpool.Pool
+}
+
+func newRatingWindowTable(db *pgxpool.Pool) *RatingWindowTable {
+	return &RatingWindowTable{
+		db,
+	}
+}
+
+func (r RatingWindowTable) Schema() string {
+	return `CREATE TABLE IF NOT EXISTS rating_window("guild_id" int8 NOT NULL UNIQUE, "hours" int4 NOT NULL, PRIMARY KEY("guild_id"));`
+}
+
+func (r *RatingWindowTable) Get(ctx context.Context, guildId uint64) (time.Duration, error) {
+	var hours int
+	if err := r.QueryRow(ctx, `SELECT "hours" FROM rating_window WHERE "guild_id" = $1;`, guildId).Scan(&hours); err != nil {
+		if err == pgx.ErrNoRows {
+			return DefaultRatingWindow, nil
+		} else {
+			return 0, err
+		}
+	}
+
+	return time.Duration(hours) * time.Hour, nil
+}
+
+func (r *RatingWindowTable) Set(ctx context.Context, guildId uint64, window time.Duration) (err error) {
+	_, err = r.Exec(ctx, `INSERT INTO rating_window("guild_id", "hours") VALUES($1, $2) ON CONFLICT("guild_id") DO UPDATE SET "hours" = $2;`, guildId, int(window.Hours()))
+	return
+}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type Table interface {
+	Schema() string
+}
+
+type DashboardTables struct {
+	RatingComments       *RatingCommentTable
+	RatingWindow         *RatingWindowTable
+	TicketNotes          *TicketNotesTable
+	TranscriptArchives   *TranscriptArchiveTable
+	TranscriptPurgeLog   *TranscriptPurgeLogTable
+	TranscriptRedactions *TranscriptRedactionTable
+	TranscriptRetention  *TranscriptRetentionTable
+	TranscriptSearch     *TranscriptSearchTable
+	TranscriptShareLinks *TranscriptShareLinkTable
+	Transcripts          *TranscriptQueries
+}
+
+func newDashboardTables(pool *pgxpool.Pool) *DashboardTables {
+	return &DashboardTables{
+		RatingComments:       newRatingCommentTable(pool),
+		RatingWindow:         newRatingWindowTable(pool),
+		TicketNotes:          newTicketNotesTable(pool),
+		TranscriptArchives:   newTranscriptArchiveTable(pool),
+		TranscriptPurgeLog:   newTranscriptPurgeLogTable(pool),
+		TranscriptRedactions: newTranscriptRedactionTable(pool),
+		TranscriptRetention:  newTranscriptRetentionTable(pool),
+		TranscriptSearch:     newTranscriptSearchTable(pool),
+		TranscriptShareLinks: newTranscriptShareLinkTable(pool),
+		Transcripts:          newTranscriptQueries(pool),
+	}
+}
+
+// CreateTables creates any of the dashboard's tables that do not exist yet
+func (d *DashboardTables) CreateTables(ctx context.Context, pool *pgxpool.Pool) error {
+	return createTables(ctx, pool,
+		d.RatingComments,
+		d.RatingWindow,
+		d.TicketNotes,
+		d.TranscriptArchives,
+		d.TranscriptPurgeLog,
+		d.TranscriptRedactions,
+		d.TranscriptRetention,
+		d.TranscriptSearch,
+		d.TranscriptShareLinks,
+	)
+}
+
+// The database may still be starting, or briefly unreachable, when the dashboard boots, so retry for a while
+const (
+	createTableAttempts   = 5
+	createTableRetryDelay = 3 * time.Second
+)
+
+func createTables(ctx context.Context, pool *pgxpool.Pool, tables ...Table) error {
+	for _, table := range tables {
+		var err error
+		for attempt := 1; attempt <= createTableAttempts; attempt++ {
+			_, err = pool.Exec(ctx, table.Schema())
+			if err == nil {
+				break
+			}
+
+			// An error returned by the server, such as a permissions error, will not go away by retrying
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) || attempt == createTableAttempts {
+				return err
+			}
+
+			time.Sleep(createTableRetryDelay)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// TicketNote is an internal note left on a ticket by a staff member. Notes are only visible to staff through the
+// dashboard, and are never sent to Discord.
+type TicketNote struct {
+	Id        int        `json:"id"`
+	GuildId   uint64     `json:"guild_id,string"`
+	TicketId  int        `json:"ticket_id"`
+	AuthorId  uint64     `json:"author_id,string"`
+	Content   string     `json:"content"`
+	CreatedAt time.Time  `json:"created_at"`
+	EditedAt  *time.Time `json:"edited_at"`
+}
+
+type TicketNotesTable struct {
+	*pgxpool.Pool
+}
+
+func newTicketNotesTable(db *pgxpool.Pool) *TicketNotesTable {
+	return &TicketNotesTable{
+		db,
+	}
+}
+
+func (t TicketNotesTable) Schema() string {
+	return `
+CREATE TABLE IF NOT EXISTS ticket_notes(
+	"id" SERIAL NOT NULL,
+	"guild_id" int8 NOT NULL,
+	"ticket_id" int4 NOT NULL,
+	"author_id" int8 NOT NULL,
+	"content" text NOT NULL,
+	"created_at" timestamptz NOT NULL,
+	"edited_at" timestamptz DEFAULT NULL,
+	PRIMARY KEY("id")
+);
+CREATE INDEX IF NOT EXISTS ticket_notes_guild_id_ticket_id ON ticket_notes("guild_id", "ticket_id");
+`
+}
+
+func (t *TicketNotesTable) Create(ctx context.Context, note TicketNote) (id int, err error) {
+	query := `
+INSERT INTO ticket_notes("guild_id", "ticket_id", "author_id", "content", "created_at")
+VALUES($1, $2, $3, $4, $5)
+RETURNING "id";
+`
+
+	err = t.QueryRow(ctx, query, note.GuildId, note.TicketId, note.AuthorId, note.Content, note.CreatedAt).Scan(&id)
+	return
+}
+
+func (t *TicketNotesTable) Get(ctx context.Context, guildId uint64, ticketId, noteId int) (TicketNote, bool, error) {
+	query := `
+SELECT "id", "guild_id", "ticket_id", "author_id", "content", "created_at", "edited_at"
+FROM ticket_notes
+WHERE "guild_id" = $1 AND "ticket_id" = $2 AND "id" = $3;
+`
+
+	note, err := scanTicketNote(t.QueryRow(ctx, query, guildId, ticketId, noteId))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return TicketNote{}, false, nil
+		} else {
+			return TicketNote{}, false, err
+		}
+	}
+
+	return note, true, nil
+}
+
+// GetByTicket returns the notes left on a ticket, oldest first
+func (t *TicketNotesTable) GetByTicket(ctx context.Context, guildId uint64, ticketId int) ([]TicketNote, error) {
+	query := `
+SELECT "id", "guild_id", "ticket_id", "author_id", "content", "created_at", "edited_at"
+FROM ticket_notes
+WHERE "guild_id" = $1 AND "ticket_id" = $2
+ORDER BY "id" ASC;
+`
+
+	rows, err := t.Query(ctx, query, guildId, ticketId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	notes := make([]TicketNote, 0)
+	for rows.Next() {
+		note, err := scanTicketNote(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		notes = append(notes, note)
+	}
+
+	return notes, rows.Err()
+}
+
+func (t *TicketNotesTable) Update(ctx context.Context, guildId uint64, ticketId, noteId int, content string, editedAt time.Time) (err error) {
+	query := `
+UPDATE ticket_notes
+SET "content" = $4, "edited_at" = $5
+WHERE "guild_id" = $1 AND "ticket_id" = $2 AND "id" = $3;
+`
+
+	_, err = t.Exec(ctx, query, guildId, ticketId, noteId, content, editedAt)
+	return
+}
+
+func (t *TicketNotesTable) Delete(ctx context.Context, guildId uint64, ticketId, noteId int) (err error) {
+	query := `DELETE FROM ticket_notes WHERE "guild_id" = $1 AND "ticket_id" = $2 AND "id" = $3;`
+
+	_, err = t.Exec(ctx, query, guildId, ticketId, noteId)
+	return
+}
+
+func scanTicketNote(row pgx.Row) (note TicketNote, err error) {
+	err = row.Scan(
+		&note.Id,
+		&note.GuildId,
+		&note.TicketId,
+		&note.AuthorId,
+		&note.Content,
+		&note.CreatedAt,
+		&note.EditedAt,
+	)
+	return
+}
@@ -0,0 +1,271 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/TicketsBot-cloud/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type TranscriptArchiveStatus string
+
+const (
+	TranscriptArchiveStatusPending  TranscriptArchiveStatus = "pending"
+	TranscriptArchiveStatusRunning  TranscriptArchiveStatus = "running"
+	TranscriptArchiveStatusComplete TranscriptArchiveStatus = "complete"
+	TranscriptArchiveStatusFailed   TranscriptArchiveStatus = "failed"
+	TranscriptArchiveStatusExpired  TranscriptArchiveStatus = "expired" // The zip file has been deleted
+)
+
+// TranscriptArchive is a request to package a guild's transcripts into a single zip file
+type TranscriptArchive struct {
+	Id           uuid.UUID               `json:"id"`
+	GuildId      uint64                  `json:"guild_id,string"`
+	RequestedBy  uint64                  `json:"requested_by,string"`
+	UserId       *uint64                 `json:"user_id,string"`
+	PanelId      *int                    `json:"panel_id"`
+	ClosedAfter  *time.Time              `json:"closed_after"`
+	ClosedBefore *time.Time              `json:"closed_before"`
+	Status       TranscriptArchiveStatus `json:"status"`
+	Total        int                     `json:"total"`
+	Processed    int                     `json:"processed"`
+	Skipped      int                     `json:"skipped"` // Tickets whose transcript could not be found
+	ObjectKey    *string                 `json:"-"`
+	Error        *string                 `json:"error"`
+	CreatedAt    time.Time               `json:"created_at"`
+	UpdatedAt    time.Time               `json:"updated_at"`
+}
+
+type TranscriptArchiveTable struct {
+	*pgxpool.Pool
+}
+
+func newTranscriptArchiveTable(db *pgxpool.Pool) *TranscriptArchiveTable {
+	return &TranscriptArchiveTable{
+		db,
+	}
+}
+
+func (t TranscriptArchiveTable) Schema() string {
+	return `
+CREATE TABLE IF NOT EXISTS transcript_archives(
+	"id" uuid NOT NULL,
+	"guild_id" int8 NOT NULL,
+	"requested_by" int8 NOT NULL,
+	"user_id" int8 DEFAULT NULL,
+	"panel_id" int4 DEFAULT NULL,
+	"closed_after" timestamptz DEFAULT NULL,
+	"closed_before" timestamptz DEFAULT NULL,
+	"status" varchar(16) NOT NULL,
+	"total" int4 NOT NULL,
+	"processed" int4 NOT NULL DEFAULT 0,
+	"skipped" int4 NOT NULL DEFAULT 0,
+	"object_key" varchar(255) DEFAULT NULL,
+	"error" text DEFAULT NULL,
+	"created_at" timestamptz NOT NULL,
+	"updated_at" timestamptz NOT NULL,
+	PRIMARY KEY("id")
+);
+CREATE INDEX IF NOT EXISTS transcript_archives_guild_id ON transcript_archives("guild_id", "created_at");
+`
+}
+
+const transcriptArchiveColumns = `"id", "guild_id", "requested_by", "user_id", "panel_id", "closed_after", "closed_before", "status", "total", "processed", "skipped", "object_key", "error", "created_at", "updated_at"`
+
+func (t *TranscriptArchiveTable) Create(ctx context.Context, archive TranscriptArchive) error {
+	query := `
+INSERT INTO transcript_archives("id", "guild_id", "requested_by", "user_id", "panel_id", "closed_after", "closed_before", "status", "total", "created_at", "updated_at")
+VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10);
+`
+
+	_, err := t.Exec(ctx, query,
+		archive.Id,
+		archive.GuildId,
+		archive.RequestedBy,
+		archive.UserId,
+		archive.PanelId,
+		archive.ClosedAfter,
+		archive.ClosedBefore,
+		archive.Status,
+		archive.Total,
+		archive.CreatedAt,
+	)
+	return err
+}
+
+func (t *TranscriptArchiveTable) Get(ctx context.Context, guildId uint64, id uuid.UUID) (TranscriptArchive, bool, error) {
+	query := `SELECT ` + transcriptArchiveColumns + ` FROM transcript_archives WHERE "guild_id" = $1 AND "id" = $2;`
+
+	archive, err := scanTranscriptArchive(t.QueryRow(ctx, query, guildId, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return TranscriptArchive{}, false, nil
+		} else {
+			return TranscriptArchive{}, false, err
+		}
+	}
+
+	return archive, true, nil
+}
+
+// GetByGuild returns the guild's most recent archives, newest first
+func (t *TranscriptArchiveTable) GetByGuild(ctx context.Context, guildId uint64, limit int) ([]TranscriptArchive, error) {
+	query := `SELECT ` + transcriptArchiveColumns + ` FROM transcript_archives WHERE "guild_id" = $1 ORDER BY "created_at" DESC LIMIT $2;`
+
+	return t.queryArchives(ctx, query, guildId, limit)
+}
+
+// HasActive returns whether the guild has an archive that is still being built. Archives that have not made progress
+// within staleAfter are assumed to have been interrupted, for example by the API restarting.
+func (t *TranscriptArchiveTable) HasActive(ctx context.Context, guildId uint64, staleAfter time.Duration) (active bool, err error) {
+	query := `
+SELECT EXISTS(
+	SELECT 1
+	FROM transcript_archives
+	WHERE "guild_id" = $1 AND "status" = ANY($2) AND "updated_at" > NOW() - $3::interval
+);
+`
+
+	statuses := []string{string(TranscriptArchiveStatusPending), string(TranscriptArchiveStatusRunning)}
+	err = t.QueryRow(ctx, query, guildId, statuses, staleAfter).Scan(&active)
+	return
+}
+
+func (t *TranscriptArchiveTable) SetProgress(ctx context.Context, id uuid.UUID, processed, skipped int) (err error) {
+	query := `
+UPDATE transcript_archives
+SET "status" = $2, "processed" = $3, "skipped" = $4, "updated_at" = NOW()
+WHERE "id" = $1;
+`
+
+	_, err = t.Exec(ctx, query, id, TranscriptArchiveStatusRunning, processed, skipped)
+	return
+}
+
+func (t *TranscriptArchiveTable) SetComplete(ctx context.Context, id uuid.UUID, processed, skipped int, objectKey string) (err error) {
+	query := `
+UPDATE transcript_archives
+SET "status" = $2, "processed" = $3, "skipped" = $4, "object_key" = $5, "updated_at" = NOW()
+WHERE "id" = $1;
+`
+
+	_, err = t.Exec(ctx, query, id, TranscriptArchiveStatusComplete, processed, skipped, objectKey)
+	return
+}
+
+func (t *TranscriptArchiveTable) SetFailed(ctx context.Context, id uuid.UUID, reason string) (err error) {
+	query := `UPDATE transcript_archives SET "status" = $2, "error" = $3, "updated_at" = NOW() WHERE "id" = $1;`
+
+	_, err = t.Exec(ctx, query, id, TranscriptArchiveStatusFailed, reason)
+	return
+}
+
+// SetExpired records that the archive's zip file has been deleted from the archive bucket
+func (t *TranscriptArchiveTable) SetExpired(ctx context.Context, id uuid.UUID) (err error) {
+	query := `UPDATE transcript_archives SET "status" = $2, "object_key" = NULL, "updated_at" = NOW() WHERE "id" = $1;`
+
+	_, err = t.Exec(ctx, query, id, TranscriptArchiveStatusExpired)
+	return
+}
+
+// GetExpired returns up to limit archives that were completed before the given time, and so whose zip file should be
+// deleted
+func (t *TranscriptArchiveTable) GetExpired(ctx context.Context, completedBefore time.Time, limit int) ([]TranscriptArchive, error) {
+	query := `
+SELECT ` + transcriptArchiveColumns + `
+FROM transcript_archives
+WHERE "status" = $1 AND "object_key" IS NOT NULL AND "updated_at" < $2
+ORDER BY "updated_at"
+LIMIT $3;
+`
+
+	return t.queryArchives(ctx, query, TranscriptArchiveStatusComplete, completedBefore, limit)
+}
+
+// GetContaining returns the guild's completed archives that may contain a ticket's transcript: those requested after
+// the ticket was closed, whose filters match the ticket.
+func (t *TranscriptArchiveTable) GetContaining(ctx context.Context, guildId uint64, ticketId int) ([]TranscriptArchive, error) {
+	query := `
+SELECT ` + transcriptArchiveColumns + `
+FROM transcript_archives
+WHERE "guild_id" = $1 AND "status" = $3 AND "object_key" IS NOT NULL AND EXISTS(
+	SELECT 1
+	FROM tickets
+	WHERE tickets.guild_id = transcript_archives.guild_id
+		AND tickets.id = $2
+		AND (tickets.close_time IS NULL OR tickets.close_time < transcript_archives.created_at)
+		AND (transcript_archives.user_id IS NULL OR tickets.user_id = transcript_archives.user_id)
+		AND (transcript_archives.panel_id IS NULL OR tickets.panel_id = transcript_archives.panel_id)
+		AND (transcript_archives.closed_after IS NULL OR tickets.close_time >= transcript_archives.closed_after)
+		AND (transcript_archives.closed_before IS NULL OR tickets.close_time < transcript_archives.closed_before)
+);
+`
+
+	return t.queryArchives(ctx, query, guildId, ticketId, TranscriptArchiveStatusComplete)
+}
+
+func (t *TranscriptArchiveTable) queryArchives(ctx context.Context, query string, args ...interface{}) ([]TranscriptArchive, error) {
+	rows, err := t.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	archives := make([]TranscriptArchive, 0)
+	for rows.Next() {
+		archive, err := scanTranscriptArchive(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		archives = append(archives, archive)
+	}
+
+	return archives, rows.Err()
+}
+
+func scanTranscriptArchive(row pgx.Row) (archive TranscriptArchive, err error) {
+	err = row.Scan(
+		&archive.Id,
+		&archive.GuildId,
+		&archive.RequestedBy,
+		&archive.UserId,
+		&archive.PanelId,
+		&archive.ClosedAfter,
+		&archive.ClosedBefore,
+		&archive.Status,
+		&archive.Total,
+		&archive.Processed,
+		&archive.Skipped,
+		&archive.ObjectKey,
+		&archive.Error,
+		&archive.CreatedAt,
+		&archive.UpdatedAt,
+	)
+	return
+}
+
+// QueryOptions returns the options selecting the transcripts to include in the archive, oldest first
+func (a TranscriptArchive) QueryOptions() TranscriptQueryOptions {
+	opts := TranscriptQueryOptions{
+		GuildId:      a.GuildId,
+		ClosedAfter:  a.ClosedAfter,
+		ClosedBefore: a.ClosedBefore,
+		Sort:         TranscriptSortId,
+		Order:        database.OrderTypeAscending,
+	}
+
+	if a.UserId != nil {
+		opts.UserIds = []uint64{*a.UserId}
+	}
+
+	if a.PanelId != nil {
+		opts.PanelIds = []int{*a.PanelId}
+	}
+
+	return opts
+}
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// TranscriptPurge records a transcript that was deleted for exceeding the guild's retention period
+type TranscriptPurge struct {
+	Id            int       `json:"id"`
+	GuildId       uint64    `json:"guild_id,string"`
+	TicketId      int       `json:"ticket_id"`
+	PanelId       *int      `json:"panel_id"`
+	RetentionDays int       `json:"retention_days"`
+	ClosedAt      time.Time `json:"closed_at"`
+	PurgedAt      time.Time `json:"purged_at"`
+}
+
+type TranscriptPurgeLogTable struct {
+	*pgxpool.Pool
+}
+
+func newTranscriptPurgeLogTable(db *pgxpool.Pool) *TranscriptPurgeLogTable {
+	return &TranscriptPurgeLogTable{
+		db,
+	}
+}
+
+func (t TranscriptPurgeLogTable) Schema() string {
+	return `
+CREATE TABLE IF NOT EXISTS transcript_purge_log(
+	"id" SERIAL NOT NULL,
+	"guild_id" int8 NOT NULL,
+	"ticket_id" int4 NOT NULL,
+	"panel_id" int4 DEFAULT NULL,
+	"retention_days" int4 NOT NULL,
+	"closed_at" timestamptz NOT NULL,
+	"purged_at" timestamptz NOT NULL,
+	PRIMARY KEY("id")
+);
+CREATE INDEX IF NOT EXISTS transcript_purge_log_guild_id ON transcript_purge_log("guild_id", "id");
+`
+}
+
+func (t *TranscriptPurgeLogTable) Create(ctx context.Context, purge TranscriptPurge) error {
+	query := `
+INSERT INTO transcript_purge_log("guild_id", "ticket_id", "panel_id", "retention_days", "closed_at", "purged_at")
+VALUES($1, $2, $3, $4, $5, $6);
+`
+
+	_, err := t.Exec(ctx, query, purge.GuildId, purge.TicketId, purge.PanelId, purge.RetentionDays, purge.ClosedAt, purge.PurgedAt)
+	return err
+}
+
+// GetByGuild returns the guild's purges, newest first. If before is non-zero, only entries with a lower ID are returned.
+func (t *TranscriptPurgeLogTable) GetByGuild(ctx context.Context, guildId uint64, before, limit int) ([]TranscriptPurge, error) {
+	query := `
+SELECT "id", "guild_id", "ticket_id", "panel_id", "retention_days", "closed_at", "purged_at"
+FROM transcript_purge_log
+WHERE "guild_id" = $1 AND ($2 = 0 OR "id" < $2)
+ORDER BY "id" DESC
+LIMIT $3;
+`
+
+	rows, err := t.Query(ctx, query, guildId, before, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	purges := make([]TranscriptPurge, 0)
+	for rows.Next() {
+		var purge TranscriptPurge
+		if err := rows.Scan(
+			&purge.Id,
+			&purge.GuildId,
+			&purge.TicketId,
+			&purge.PanelId,
+			&purge.RetentionDays,
+			&purge.ClosedAt,
+			&purge.PurgedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		purges = append(purges, purge)
+	}
+
+	return purges, rows.Err()
+}
@@ -0,0 +1,288 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/TicketsBot-cloud/database"
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type TranscriptSort string
+
+const (
+	TranscriptSortId TranscriptSort = "id"
+
+	// TranscriptSortCloseTime sorts by close time, falling back to the open time for tickets without one
+	TranscriptSortCloseTime TranscriptSort = "close_time"
+)
+
+// TranscriptCursor is the sort key of the last transcript on the previous page
+type TranscriptCursor struct {
+	Id   int        `json:"id"`
+	Time *time.Time `json:"time,omitempty"` // Only set when sorting by close time
+}
+
+type TranscriptQueryOptions struct {
+	GuildId      uint64
+	Id           int
+	UserIds      []uint64
+	PanelIds     []int
+	Rating       int
+	ClosedById   uint64
+	ClaimedById  uint64
+	CloseReason  string // Case-insensitive substring match
+	OpenedAfter  *time.Time
+	OpenedBefore *time.Time
+	ClosedAfter  *time.Time
+	ClosedBefore *time.Time
+
+	Sort  TranscriptSort
+	Order database.OrderType
+
+	// After selects the page following the cursor, using the sort key rather than an offset, so that deep pages are
+	// as cheap as the first
+	After *TranscriptCursor
+	Limit int
+}
+
+// TranscriptQueries queries closed tickets with filters the shared database module does not support. It only reads
+// tables owned by the shared module, so has no schema of its own.
+//
+// Sorting by close time relies on an index on the tickets table, which must be created by hand as the table is owned
+// by the shared module. It is built concurrently so that the bot can continue writing to the table:
+//
+//	CREATE INDEX CONCURRENTLY IF NOT EXISTS tickets_guild_id_closed_sort_time
+//		ON tickets("guild_id", (COALESCE("close_time", "open_time")), "id") WHERE "open" = 'f';
+//
+// If the build fails, the index is left invalid and must be dropped before retrying.
+type TranscriptQueries struct {
+	*pgxpool.Pool
+}
+
+func newTranscriptQueries(db *pgxpool.Pool) *TranscriptQueries {
+	return &TranscriptQueries{
+		db,
+	}
+}
+
+const transcriptSortTime = `COALESCE(tickets.close_time, tickets.open_time)`
+
+// transcriptColumns are the columns of the tickets table needed to list transcripts, and the fields of database.Ticket
+// they are scanned into. Other fields of the returned tickets are left zero.
+var transcriptColumns = []struct {
+	Name  string
+	Field func(ticket *database.Ticket) interface{}
+}{
+	{"tickets.id", func(ticket *database.Ticket) interface{} { return &ticket.Id }},
+	{"tickets.guild_id", func(ticket *database.Ticket) interface{} { return &ticket.GuildId }},
+	{"tickets.user_id", func(ticket *database.Ticket) interface{} { return &ticket.UserId }},
+	{"tickets.open", func(ticket *database.Ticket) interface{} { return &ticket.Open }},
+	{"tickets.open_time", func(ticket *database.Ticket) interface{} { return &ticket.OpenTime }},
+	{"tickets.panel_id", func(ticket *database.Ticket) interface{} { return &ticket.PanelId }},
+	{"tickets.has_transcript", func(ticket *database.Ticket) interface{} { return &ticket.HasTranscript }},
+	{"tickets.close_time", func(ticket *database.Ticket) interface{} { return &ticket.CloseTime }},
+}
+
+func transcriptColumnNames() string {
+	names := make([]string, len(transcriptColumns))
+	for i, column := range transcriptColumns {
+		names[i] = column.Name
+	}
+
+	return strings.Join(names, ", ")
+}
+
+func scanTranscript(rows pgx.Rows) (database.Ticket, error) {
+	var ticket database.Ticket
+
+	fields := make([]interface{}, len(transcriptColumns))
+	for i, column := range transcriptColumns {
+		fields[i] = column.Field(&ticket)
+	}
+
+	err := rows.Scan(fields...)
+	return ticket, err
+}
+
+// Get returns the closed tickets matching the options. Only the fields in transcriptColumns are populated.
+func (t *TranscriptQueries) Get(ctx context.Context, opts TranscriptQueryOptions) ([]database.Ticket, error) {
+	from, where, args, err := opts.buildFilters()
+	if err != nil {
+		return nil, err
+	}
+
+	// Cannot use prepared statement for the order
+	order := "DESC"
+	comparison := "<"
+	if opts.Order == database.OrderTypeAscending {
+		order = "ASC"
+		comparison = ">"
+	}
+
+	if opts.After != nil {
+		if opts.Sort == TranscriptSortCloseTime {
+			if opts.After.Time == nil {
+				return nil, fmt.Errorf("cursor is missing time")
+			}
+
+			args = append(args, *opts.After.Time, opts.After.Id)
+			where = append(where, fmt.Sprintf(`(%s, tickets.id) %s ($%d, $%d)`, transcriptSortTime, comparison, len(args)-1, len(args)))
+		} else {
+			args = append(args, opts.After.Id)
+			where = append(where, fmt.Sprintf(`tickets.id %s $%d`, comparison, len(args)))
+		}
+	}
+
+	query := "SELECT " + transcriptColumnNames() + " " + from + " WHERE " + strings.Join(where, " AND ")
+
+	if opts.Sort == TranscriptSortCloseTime {
+		query += fmt.Sprintf(" ORDER BY %s %s, tickets.id %s", transcriptSortTime, order, order)
+	} else {
+		query += fmt.Sprintf(" ORDER BY tickets.id %s", order)
+	}
+
+	if opts.Limit != 0 {
+		args = append(args, opts.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := t.Query(ctx, query+";", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var tickets []database.Ticket
+	for rows.Next() {
+		ticket, err := scanTranscript(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		tickets = append(tickets, ticket)
+	}
+
+	return tickets, rows.Err()
+}
+
+// Count returns the number of transcripts matching the filters, ignoring paging. This requires visiting every
+// matching row, so should only be requested when needed.
+func (t *TranscriptQueries) Count(ctx context.Context, opts TranscriptQueryOptions) (count int, err error) {
+	from, where, args, err := opts.buildFilters()
+	if err != nil {
+		return 0, err
+	}
+
+	query := "SELECT COUNT(*) " + from + " WHERE " + strings.Join(where, " AND ") + ";"
+	err = t.QueryRow(ctx, query, args...).Scan(&count)
+	return
+}
+
+// CursorFor returns the cursor pointing after the given ticket
+func (o TranscriptQueryOptions) CursorFor(ticket database.Ticket) TranscriptCursor {
+	cursor := TranscriptCursor{
+		Id: ticket.Id,
+	}
+
+	if o.Sort == TranscriptSortCloseTime {
+		if ticket.CloseTime != nil {
+			cursor.Time = ticket.CloseTime
+		} else {
+			cursor.Time = &ticket.OpenTime
+		}
+	}
+
+	return cursor
+}
+
+func (o TranscriptQueryOptions) buildFilters() (from string, where []string, args []interface{}, _err error) {
+	from = "FROM tickets"
+
+	if o.Rating != 0 {
+		from += " INNER JOIN service_ratings ON tickets.guild_id = service_ratings.guild_id AND tickets.id = service_ratings.ticket_id"
+	}
+
+	if o.ClosedById != 0 || o.CloseReason != "" {
+		from += " INNER JOIN close_reason ON tickets.guild_id = close_reason.guild_id AND tickets.id = close_reason.ticket_id"
+	}
+
+	if o.ClaimedById != 0 {
+		from += " INNER JOIN ticket_claims ON tickets.guild_id = ticket_claims.guild_id AND tickets.id = ticket_claims.ticket_id"
+	}
+
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		where = append(where, fmt.Sprintf(condition, len(args)))
+	}
+
+	add(`tickets.guild_id = $%d`, o.GuildId)
+	where = append(where, `tickets.open = 'f'`)
+
+	if o.Id != 0 {
+		add(`tickets.id = $%d`, o.Id)
+	}
+
+	if len(o.UserIds) > 0 {
+		userIdArray := &pgtype.Int8Array{}
+		if err := userIdArray.Set(o.UserIds); err != nil {
+			return "", nil, nil, err
+		}
+
+		add(`tickets.user_id = ANY($%d)`, userIdArray)
+	}
+
+	if len(o.PanelIds) > 0 {
+		panelIdArray := &pgtype.Int4Array{}
+		if err := panelIdArray.Set(o.PanelIds); err != nil {
+			return "", nil, nil, err
+		}
+
+		add(`tickets.panel_id = ANY($%d)`, panelIdArray)
+	}
+
+	if o.Rating != 0 {
+		add(`service_ratings.rating = $%d`, o.Rating)
+	}
+
+	if o.ClosedById != 0 {
+		add(`close_reason.closed_by = $%d`, o.ClosedById)
+	}
+
+	if o.CloseReason != "" {
+		add(`close_reason.close_reason ILIKE '%%' || $%d || '%%'`, escapeLikePattern(o.CloseReason))
+	}
+
+	if o.ClaimedById != 0 {
+		add(`ticket_claims.user_id = $%d`, o.ClaimedById)
+	}
+
+	if o.OpenedAfter != nil {
+		add(`tickets.open_time >= $%d`, *o.OpenedAfter)
+	}
+
+	if o.OpenedBefore != nil {
+		add(`tickets.open_time < $%d`, *o.OpenedBefore)
+	}
+
+	if o.ClosedAfter != nil {
+		add(`tickets.close_time >= $%d`, *o.ClosedAfter)
+	}
+
+	if o.ClosedBefore != nil {
+		add(`tickets.close_time < $%d`, *o.ClosedBefore)
+	}
+
+	return
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func escapeLikePattern(s string) string {
+	return likeEscaper.Replace(s)
+}
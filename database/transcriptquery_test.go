@@ -0,0 +1,48 @@
+package database
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/TicketsBot-cloud/database"
+	"github.com/stretchr/testify/assert"
+)
+
+// Each column must be scanned into the field of database.Ticket with the same name, as the column list is kept
+// separately from the shared database module's.
+func TestTranscriptColumnsMapping(t *testing.T) {
+	var ticket database.Ticket
+	value := reflect.ValueOf(&ticket).Elem()
+
+	seen := make(map[string]bool)
+	for _, column := range transcriptColumns {
+		name := strings.TrimPrefix(column.Name, "tickets.")
+		assert.False(t, seen[name], "column %s is selected twice", name)
+		seen[name] = true
+
+		field, ok := fieldByJsonTag(value, name)
+		if !assert.True(t, ok, "database.Ticket has no field for column %s", name) {
+			continue
+		}
+
+		target := reflect.ValueOf(column.Field(&ticket))
+		assert.Equal(t, field.Addr().Type(), target.Type(), "column %s is scanned into the wrong type", name)
+		assert.Equal(t, field.Addr().Pointer(), target.Pointer(), "column %s is scanned into the wrong field", name)
+	}
+
+	// Needed to build the cursor for each sort
+	for _, name := range []string{"id", "open_time", "close_time"} {
+		assert.True(t, seen[name], "column %s is not selected", name)
+	}
+}
+
+func fieldByJsonTag(value reflect.Value, tag string) (reflect.Value, bool) {
+	for i := 0; i < value.NumField(); i++ {
+		if strings.Split(value.Type().Field(i).Tag.Get("json"), ",")[0] == tag {
+			return value.Field(i), true
+		}
+	}
+
+	return reflect.Value{}, false
+}
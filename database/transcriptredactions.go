@@ -0,0 +1,113 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type TranscriptRedactionStatus string
+
+const (
+	TranscriptRedactionStatusPending  TranscriptRedactionStatus = "pending"
+	TranscriptRedactionStatusApplied  TranscriptRedactionStatus = "applied" // Re-uploaded, but copies may remain elsewhere
+	TranscriptRedactionStatusComplete TranscriptRedactionStatus = "complete"
+	TranscriptRedactionStatusFailed   TranscriptRedactionStatus = "failed" // The stored transcript was not changed
+)
+
+// TranscriptRedaction records a message, or a single attachment of a message, that was redacted from a transcript
+type TranscriptRedaction struct {
+	Id           int                       `json:"id"`
+	GuildId      uint64                    `json:"guild_id,string"`
+	TicketId     int                       `json:"ticket_id"`
+	MessageId    uint64                    `json:"message_id,string"`
+	AttachmentId *uint64                   `json:"attachment_id,string"` // Nil if the whole message was redacted
+	RedactedBy   uint64                    `json:"redacted_by,string"`
+	RedactedAt   time.Time                 `json:"redacted_at"`
+	Status       TranscriptRedactionStatus `json:"status"`
+}
+
+type TranscriptRedactionTable struct {
+	*pgxpool.Pool
+}
+
+func newTranscriptRedactionTable(db *pgxpool.Pool) *TranscriptRedactionTable {
+	return &TranscriptRedactionTable{
+		db,
+	}
+}
+
+func (t TranscriptRedactionTable) Schema() string {
+	return `
+CREATE TABLE IF NOT EXISTS transcript_redactions(
+	"id" SERIAL NOT NULL,
+	"guild_id" int8 NOT NULL,
+	"ticket_id" int4 NOT NULL,
+	"message_id" int8 NOT NULL,
+	"attachment_id" int8 DEFAULT NULL,
+	"redacted_by" int8 NOT NULL,
+	"redacted_at" timestamptz NOT NULL,
+	"status" varchar(16) NOT NULL,
+	PRIMARY KEY("id")
+);
+CREATE INDEX IF NOT EXISTS transcript_redactions_guild_id_ticket_id ON transcript_redactions("guild_id", "ticket_id");
+`
+}
+
+// Create records the redaction, returning its ID
+func (t *TranscriptRedactionTable) Create(ctx context.Context, redaction TranscriptRedaction) (id int, err error) {
+	query := `
+INSERT INTO transcript_redactions("guild_id", "ticket_id", "message_id", "attachment_id", "redacted_by", "redacted_at", "status")
+VALUES($1, $2, $3, $4, $5, $6, $7)
+RETURNING "id";
+`
+
+	err = t.QueryRow(ctx, query, redaction.GuildId, redaction.TicketId, redaction.MessageId, redaction.AttachmentId, redaction.RedactedBy, redaction.RedactedAt, redaction.Status).Scan(&id)
+	return
+}
+
+func (t *TranscriptRedactionTable) SetStatus(ctx context.Context, id int, status TranscriptRedactionStatus) error {
+	query := `UPDATE transcript_redactions SET "status" = $2 WHERE "id" = $1;`
+
+	_, err := t.Exec(ctx, query, id, status)
+	return err
+}
+
+// GetByTicket returns the redactions made to a ticket's transcript, oldest first
+func (t *TranscriptRedactionTable) GetByTicket(ctx context.Context, guildId uint64, ticketId int) ([]TranscriptRedaction, error) {
+	query := `
+SELECT "id", "guild_id", "ticket_id", "message_id", "attachment_id", "redacted_by", "redacted_at", "status"
+FROM transcript_redactions
+WHERE "guild_id" = $1 AND "ticket_id" = $2
+ORDER BY "id" ASC;
+`
+
+	rows, err := t.Query(ctx, query, guildId, ticketId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	redactions := make([]TranscriptRedaction, 0)
+	for rows.Next() {
+		var redaction TranscriptRedaction
+		if err := rows.Scan(
+			&redaction.Id,
+			&redaction.GuildId,
+			&redaction.TicketId,
+			&redaction.MessageId,
+			&redaction.AttachmentId,
+			&redaction.RedactedBy,
+			&redaction.RedactedAt,
+			&redaction.Status,
+		); err != nil {
+			return nil, err
+		}
+
+		redactions = append(redactions, redaction)
+	}
+
+	return redactions, rows.Err()
+}
@@ -0,0 +1,214 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// TranscriptRetention is how many days after closing a guild's transcripts are kept for. Panels may override the guild
+// wide period, which is nil if transcripts are kept forever.
+type TranscriptRetention struct {
+	Days   *int        `json:"days"`
+	Panels map[int]int `json:"panels"`
+}
+
+// ExpiredTranscript is a transcript that has outlived the retention period that applies to it
+type ExpiredTranscript struct {
+	GuildId       uint64
+	TicketId      int
+	PanelId       *int
+	RetentionDays int
+	ClosedAt      time.Time
+}
+
+type TranscriptRetentionTable struct {
+	*pgxpool.Pool
+}
+
+func newTranscriptRetentionTable(db *pgxpool.Pool) *TranscriptRetentionTable {
+	return &TranscriptRetentionTable{
+		db,
+	}
+}
+
+func (t TranscriptRetentionTable) Schema() string {
+	return `
+CREATE TABLE IF NOT EXISTS transcript_retention(
+	"guild_id" int8 NOT NULL,
+	"days" int4 NOT NULL,
+	PRIMARY KEY("guild_id")
+);
+CREATE TABLE IF NOT EXISTS transcript_retention_panels(
+	"guild_id" int8 NOT NULL,
+	"panel_id" int4 NOT NULL,
+	"days" int4 NOT NULL,
+	FOREIGN KEY("panel_id") REFERENCES panels("panel_id") ON DELETE CASCADE,
+	PRIMARY KEY("guild_id", "panel_id")
+);
+CREATE TABLE IF NOT EXISTS transcript_retention_failures(
+	"guild_id" int8 NOT NULL,
+	"ticket_id" int4 NOT NULL,
+	"attempts" int4 NOT NULL,
+	"retry_after" timestamptz NOT NULL,
+	PRIMARY KEY("guild_id", "ticket_id")
+);
+`
+}
+
+func (t *TranscriptRetentionTable) Get(ctx context.Context, guildId uint64) (TranscriptRetention, error) {
+	retention := TranscriptRetention{
+		Panels: make(map[int]int),
+	}
+
+	var days int
+	if err := t.QueryRow(ctx, `SELECT "days" FROM transcript_retention WHERE "guild_id" = $1;`, guildId).Scan(&days); err != nil {
+		if err != pgx.ErrNoRows {
+			return TranscriptRetention{}, err
+		}
+	} else {
+		retention.Days = &days
+	}
+
+	rows, err := t.Query(ctx, `SELECT "panel_id", "days" FROM transcript_retention_panels WHERE "guild_id" = $1;`, guildId)
+	if err != nil {
+		return TranscriptRetention{}, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var panelId, days int
+		if err := rows.Scan(&panelId, &days); err != nil {
+			return TranscriptRetention{}, err
+		}
+
+		retention.Panels[panelId] = days
+	}
+
+	return retention, rows.Err()
+}
+
+// Set replaces the guild's retention configuration, including all panel overrides
+func (t *TranscriptRetentionTable) Set(ctx context.Context, guildId uint64, retention TranscriptRetention) error {
+	tx, err := t.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(ctx) // Does not matter if commit succeeds
+
+	if retention.Days == nil {
+		_, err = tx.Exec(ctx, `DELETE FROM transcript_retention WHERE "guild_id" = $1;`, guildId)
+	} else {
+		_, err = tx.Exec(ctx, `
+INSERT INTO transcript_retention("guild_id", "days")
+VALUES($1, $2)
+ON CONFLICT("guild_id") DO UPDATE SET "days" = EXCLUDED."days";`, guildId, *retention.Days)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM transcript_retention_panels WHERE "guild_id" = $1;`, guildId); err != nil {
+		return err
+	}
+
+	for panelId, days := range retention.Panels {
+		query := `INSERT INTO transcript_retention_panels("guild_id", "panel_id", "days") VALUES($1, $2, $3);`
+		if _, err := tx.Exec(ctx, query, guildId, panelId, days); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetExpired returns transcripts across all guilds that are older than their retention period, oldest first. A panel's
+// override takes precedence over the guild wide period. Tickets closed before close times were recorded are aged by
+// their open time instead. Transcripts that recently failed to purge are excluded until their backoff has elapsed, so
+// that they do not fill every batch.
+func (t *TranscriptRetentionTable) GetExpired(ctx context.Context, limit int) ([]ExpiredTranscript, error) {
+	query := `
+SELECT tickets.guild_id, tickets.id, tickets.panel_id, expired.days, expired.closed_at
+FROM tickets
+LEFT JOIN transcript_retention
+	ON transcript_retention.guild_id = tickets.guild_id
+LEFT JOIN transcript_retention_panels
+	ON transcript_retention_panels.guild_id = tickets.guild_id AND transcript_retention_panels.panel_id = tickets.panel_id
+CROSS JOIN LATERAL (
+	SELECT
+		COALESCE(transcript_retention_panels.days, transcript_retention.days) AS days,
+		COALESCE(tickets.close_time, tickets.open_time) AS closed_at
+) AS expired
+WHERE tickets.guild_id IN (
+	SELECT "guild_id" FROM transcript_retention
+	UNION
+	SELECT "guild_id" FROM transcript_retention_panels
+)
+	AND tickets.open = 'f'
+	AND tickets.has_transcript = 't'
+	AND expired.days IS NOT NULL
+	AND expired.closed_at < NOW() - make_interval(days => expired.days)
+	AND NOT EXISTS (
+		SELECT 1
+		FROM transcript_retention_failures
+		WHERE transcript_retention_failures.guild_id = tickets.guild_id
+			AND transcript_retention_failures.ticket_id = tickets.id
+			AND transcript_retention_failures.retry_after > NOW()
+	)
+ORDER BY expired.closed_at ASC
+LIMIT $1;
+`
+
+	rows, err := t.Query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var expired []ExpiredTranscript
+	for rows.Next() {
+		var transcript ExpiredTranscript
+		if err := rows.Scan(
+			&transcript.GuildId,
+			&transcript.TicketId,
+			&transcript.PanelId,
+			&transcript.RetentionDays,
+			&transcript.ClosedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		expired = append(expired, transcript)
+	}
+
+	return expired, rows.Err()
+}
+
+// RecordFailure records a failed attempt to purge a transcript, excluding it from GetExpired for an exponential backoff
+// that starts at an hour and is capped at a day.
+func (t *TranscriptRetentionTable) RecordFailure(ctx context.Context, guildId uint64, ticketId int) error {
+	query := `
+INSERT INTO transcript_retention_failures("guild_id", "ticket_id", "attempts", "retry_after")
+VALUES($1, $2, 1, NOW() + INTERVAL '1 hour')
+ON CONFLICT("guild_id", "ticket_id") DO UPDATE SET
+	"attempts" = transcript_retention_failures.attempts + 1,
+	"retry_after" = NOW() + LEAST(INTERVAL '1 hour' * POWER(2, transcript_retention_failures.attempts), INTERVAL '1 day');
+`
+
+	_, err := t.Exec(ctx, query, guildId, ticketId)
+	return err
+}
+
+// ClearFailure removes any failed attempts recorded for a transcript, once it has been purged
+func (t *TranscriptRetentionTable) ClearFailure(ctx context.Context, guildId uint64, ticketId int) error {
+	query := `DELETE FROM transcript_retention_failures WHERE "guild_id" = $1 AND "ticket_id" = $2;`
+
+	_, err := t.Exec(ctx, query, guildId, ticketId)
+	return err
+}
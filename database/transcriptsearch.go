@@ -0,0 +1,382 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// ErrTranscriptSearchDisabled is returned when indexing a transcript for a guild that has not enabled search
+var ErrTranscriptSearchDisabled = errors.New("transcript search is not enabled for this guild")
+
+// Highlighted terms in search snippets are wrapped in these markers, rather than HTML, as the message content is not
+// escaped. They are in the Unicode private use area, and are stripped from content before it is highlighted.
+const (
+	HighlightStart = "\uE000"
+	HighlightEnd   = "\uE001"
+)
+
+type TranscriptSearchMessage struct {
+	Id        uint64
+	AuthorId  uint64
+	Content   string
+	Timestamp time.Time
+}
+
+type TranscriptSearchMatch struct {
+	TicketId  int
+	MessageId uint64
+	AuthorId  uint64
+	Timestamp time.Time
+}
+
+type TranscriptSearchTable struct {
+	*pgxpool.Pool
+}
+
+func newTranscriptSearchTable(db *pgxpool.Pool) *TranscriptSearchTable {
+	return &TranscriptSearchTable{
+		db,
+	}
+}
+
+// The simple configuration is used, rather than a language, as transcripts are in many languages and are mostly
+// searched for identifiers such as order numbers, which should not be stemmed.
+//
+// Only the tsvector of each message is stored, rather than its content. However, the simple configuration keeps every
+// word of the message verbatim, so the index contains the plaintext of transcripts that the archiver encrypts at rest,
+// including anything sensitive pasted into a ticket. Indexing is therefore opt-in: only guilds in
+// transcript_search_guilds are indexed, and disabling search deletes the guild's index.
+func (t TranscriptSearchTable) Schema() string {
+	return `
+CREATE TABLE IF NOT EXISTS transcript_search(
+	"guild_id" int8 NOT NULL,
+	"ticket_id" int4 NOT NULL,
+	"message_id" int8 NOT NULL,
+	"author_id" int8 NOT NULL,
+	"timestamp" timestamptz NOT NULL,
+	"document" tsvector NOT NULL,
+	PRIMARY KEY("guild_id", "ticket_id", "message_id")
+);
+CREATE INDEX IF NOT EXISTS transcript_search_document ON transcript_search USING GIN("document");
+
+CREATE TABLE IF NOT EXISTS transcript_search_guilds(
+	"guild_id" int8 NOT NULL,
+	"enabled_by" int8 NOT NULL,
+	"enabled_at" timestamptz NOT NULL,
+	PRIMARY KEY("guild_id")
+);
+
+CREATE TABLE IF NOT EXISTS transcript_search_tickets(
+	"guild_id" int8 NOT NULL,
+	"ticket_id" int4 NOT NULL,
+	"indexed_at" timestamptz NOT NULL,
+	"failed" bool NOT NULL DEFAULT 'f',
+	PRIMARY KEY("guild_id", "ticket_id")
+);
+`
+}
+
+func (t *TranscriptSearchTable) IsEnabled(ctx context.Context, guildId uint64) (enabled bool, err error) {
+	query := `SELECT EXISTS(SELECT 1 FROM transcript_search_guilds WHERE "guild_id" = $1);`
+	err = t.QueryRow(ctx, query, guildId).Scan(&enabled)
+	return
+}
+
+// Enable opts the guild in to having its transcripts indexed. Existing transcripts are indexed by the backfill.
+func (t *TranscriptSearchTable) Enable(ctx context.Context, guildId, enabledBy uint64) error {
+	query := `
+INSERT INTO transcript_search_guilds("guild_id", "enabled_by", "enabled_at")
+VALUES($1, $2, NOW())
+ON CONFLICT("guild_id") DO NOTHING;
+`
+
+	_, err := t.Exec(ctx, query, guildId, enabledBy)
+	return err
+}
+
+// Disable opts the guild out of search, and deletes everything indexed for it. Waits for any transcript being indexed
+// for the guild to be committed first, so that it is deleted too.
+func (t *TranscriptSearchTable) Disable(ctx context.Context, guildId uint64) error {
+	tx, err := t.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(ctx) // Does not matter if commit succeeds
+
+	query := `DELETE FROM transcript_search_guilds WHERE "guild_id" = $1;`
+	if _, err := tx.Exec(ctx, query, guildId); err != nil {
+		return err
+	}
+
+	query = `DELETE FROM transcript_search WHERE "guild_id" = $1;`
+	if _, err := tx.Exec(ctx, query, guildId); err != nil {
+		return err
+	}
+
+	query = `DELETE FROM transcript_search_tickets WHERE "guild_id" = $1;`
+	if _, err := tx.Exec(ctx, query, guildId); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// lockEnabled returns ErrTranscriptSearchDisabled if the guild has not enabled search, and otherwise prevents it from
+// being disabled until the transaction ends.
+func lockEnabled(ctx context.Context, tx pgx.Tx, guildId uint64) error {
+	query := `SELECT 1 FROM transcript_search_guilds WHERE "guild_id" = $1 FOR SHARE;`
+
+	var exists int
+	if err := tx.QueryRow(ctx, query, guildId).Scan(&exists); err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrTranscriptSearchDisabled
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// Index replaces the indexed messages of a ticket, and marks the ticket as indexed, even if it has no messages.
+// Returns ErrTranscriptSearchDisabled if the guild has not enabled search.
+func (t *TranscriptSearchTable) Index(ctx context.Context, guildId uint64, ticketId int, messages []TranscriptSearchMessage) error {
+	tx, err := t.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(ctx) // Does not matter if commit succeeds
+
+	if err := lockEnabled(ctx, tx, guildId); err != nil {
+		return err
+	}
+
+	query := `DELETE FROM transcript_search WHERE "guild_id" = $1 AND "ticket_id" = $2;`
+	if _, err := tx.Exec(ctx, query, guildId, ticketId); err != nil {
+		return err
+	}
+
+	var messageIds, authorIds []int64
+	var timestamps []time.Time
+	var contents []string
+	for _, message := range messages {
+		if len(strings.TrimSpace(message.Content)) == 0 {
+			continue
+		}
+
+		messageIds = append(messageIds, int64(message.Id))
+		authorIds = append(authorIds, int64(message.AuthorId))
+		timestamps = append(timestamps, message.Timestamp)
+		contents = append(contents, message.Content)
+	}
+
+	if len(messageIds) > 0 {
+		// The content is only passed to Postgres to build the tsvector, and is never stored
+		query = `
+INSERT INTO transcript_search("guild_id", "ticket_id", "message_id", "author_id", "timestamp", "document")
+SELECT $1, $2, messages.id, messages.author_id, messages.timestamp, to_tsvector('simple', messages.content)
+FROM unnest($3::int8[], $4::int8[], $5::timestamptz[], $6::text[]) AS messages(id, author_id, timestamp, content)
+ON CONFLICT("guild_id", "ticket_id", "message_id") DO NOTHING;
+`
+		if _, err := tx.Exec(ctx, query, guildId, ticketId, messageIds, authorIds, timestamps, contents); err != nil {
+			return err
+		}
+	}
+
+	query = `
+INSERT INTO transcript_search_tickets("guild_id", "ticket_id", "indexed_at", "failed")
+VALUES($1, $2, NOW(), 'f')
+ON CONFLICT("guild_id", "ticket_id") DO UPDATE SET "indexed_at" = NOW(), "failed" = 'f';
+`
+	if _, err := tx.Exec(ctx, query, guildId, ticketId); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// MarkFailed records that a ticket's transcript could not be indexed, so that it is not returned by GetUnindexed
+// again and does not hold up the tickets behind it. Any messages already indexed for the ticket are kept. Nothing is
+// recorded if the guild has not enabled search.
+func (t *TranscriptSearchTable) MarkFailed(ctx context.Context, guildId uint64, ticketId int) error {
+	query := `
+INSERT INTO transcript_search_tickets("guild_id", "ticket_id", "indexed_at", "failed")
+SELECT $1, $2, NOW(), 't'
+WHERE EXISTS(SELECT 1 FROM transcript_search_guilds WHERE "guild_id" = $1)
+ON CONFLICT("guild_id", "ticket_id") DO UPDATE SET "indexed_at" = NOW(), "failed" = 't';
+`
+
+	_, err := t.Exec(ctx, query, guildId, ticketId)
+	return err
+}
+
+func (t *TranscriptSearchTable) Delete(ctx context.Context, guildId uint64, ticketId int) error {
+	tx, err := t.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(ctx) // Does not matter if commit succeeds
+
+	query := `DELETE FROM transcript_search WHERE "guild_id" = $1 AND "ticket_id" = $2;`
+	if _, err := tx.Exec(ctx, query, guildId, ticketId); err != nil {
+		return err
+	}
+
+	query = `DELETE FROM transcript_search_tickets WHERE "guild_id" = $1 AND "ticket_id" = $2;`
+	if _, err := tx.Exec(ctx, query, guildId, ticketId); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetUnindexed returns the IDs of the guild's most recent closed tickets that have a transcript, but have not been
+// indexed yet, such as those closed before search was enabled. Tickets marked as failed are not returned.
+func (t *TranscriptSearchTable) GetUnindexed(ctx context.Context, guildId uint64, limit int) ([]int, error) {
+	query := `
+SELECT tickets.id
+FROM tickets
+WHERE tickets.guild_id = $1
+	AND tickets.open = 'f'
+	AND tickets.has_transcript = 't'
+	AND NOT EXISTS (
+		SELECT 1
+		FROM transcript_search_tickets
+		WHERE transcript_search_tickets.guild_id = tickets.guild_id AND transcript_search_tickets.ticket_id = tickets.id
+	)
+ORDER BY tickets.id DESC
+LIMIT $2;
+`
+
+	rows, err := t.Query(ctx, query, guildId, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ticketIds []int
+	for rows.Next() {
+		var ticketId int
+		if err := rows.Scan(&ticketId); err != nil {
+			return nil, err
+		}
+
+		ticketIds = append(ticketIds, ticketId)
+	}
+
+	return ticketIds, rows.Err()
+}
+
+// GetEnabledGuilds returns the IDs of the guilds that have enabled search
+func (t *TranscriptSearchTable) GetEnabledGuilds(ctx context.Context) ([]uint64, error) {
+	query := `SELECT "guild_id" FROM transcript_search_guilds;`
+
+	rows, err := t.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var guildIds []uint64
+	for rows.Next() {
+		var guildId uint64
+		if err := rows.Scan(&guildId); err != nil {
+			return nil, err
+		}
+
+		guildIds = append(guildIds, guildId)
+	}
+
+	return guildIds, rows.Err()
+}
+
+// Search returns the messages matching a web search style query (quoted phrases, OR, -exclusions), grouped by ticket,
+// newest ticket first. ticketLimit and offset page over tickets, rather than messages, and at most snippetsPerTicket
+// matches are returned for each ticket.
+func (t *TranscriptSearchTable) Search(ctx context.Context, guildId uint64, searchQuery string, ticketLimit, offset, snippetsPerTicket int) ([]TranscriptSearchMatch, error) {
+	query := `
+WITH search AS (
+	SELECT websearch_to_tsquery('simple', $2) AS query
+), matches AS (
+	SELECT transcript_search.ticket_id, transcript_search.message_id, transcript_search.author_id, transcript_search.timestamp,
+		ROW_NUMBER() OVER (PARTITION BY transcript_search.ticket_id ORDER BY transcript_search.message_id) AS n
+	FROM transcript_search, search
+	WHERE transcript_search.guild_id = $1 AND transcript_search.document @@ search.query
+), matched_tickets AS (
+	SELECT DISTINCT matches.ticket_id
+	FROM matches
+	ORDER BY matches.ticket_id DESC
+	LIMIT $3 OFFSET $4
+)
+SELECT matches.ticket_id, matches.message_id, matches.author_id, matches.timestamp
+FROM matches
+INNER JOIN matched_tickets ON matches.ticket_id = matched_tickets.ticket_id
+WHERE matches.n <= $5
+ORDER BY matches.ticket_id DESC, matches.message_id;
+`
+
+	rows, err := t.Query(ctx, query, guildId, searchQuery, ticketLimit, offset, snippetsPerTicket)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var matches []TranscriptSearchMatch
+	for rows.Next() {
+		var match TranscriptSearchMatch
+		if err := rows.Scan(&match.TicketId, &match.MessageId, &match.AuthorId, &match.Timestamp); err != nil {
+			return nil, err
+		}
+
+		matches = append(matches, match)
+	}
+
+	return matches, rows.Err()
+}
+
+// Highlight returns a snippet of each of contents, with the terms matching the search query wrapped in HighlightStart
+// and HighlightEnd.
+func (t *TranscriptSearchTable) Highlight(ctx context.Context, searchQuery string, contents []string) ([]string, error) {
+	query := `
+SELECT ts_headline('simple', contents.content, websearch_to_tsquery('simple', $1), $2)
+FROM unnest($3::text[]) WITH ORDINALITY AS contents(content, n)
+ORDER BY contents.n;
+`
+
+	options := "StartSel=" + HighlightStart + ", StopSel=" + HighlightEnd + ", MaxWords=35, MinWords=15, MaxFragments=2"
+
+	stripped := make([]string, len(contents))
+	for i, content := range contents {
+		stripped[i] = strings.NewReplacer(HighlightStart, "", HighlightEnd, "").Replace(content)
+	}
+
+	rows, err := t.Query(ctx, query, searchQuery, options, stripped)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	snippets := make([]string, 0, len(contents))
+	for rows.Next() {
+		var snippet string
+		if err := rows.Scan(&snippet); err != nil {
+			return nil, err
+		}
+
+		snippets = append(snippets, snippet)
+	}
+
+	return snippets, rows.Err()
+}
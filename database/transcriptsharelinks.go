@@ -0,0 +1,174 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type TranscriptShareLink struct {
+	Id        uuid.UUID  `json:"id"`
+	GuildId   uint64     `json:"guild_id,string"`
+	TicketId  int        `json:"ticket_id"`
+	CreatedBy uint64     `json:"created_by,string"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	SingleUse bool       `json:"single_use"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+type TranscriptShareLinkTable struct {
+	*pgxpool.Pool
+}
+
+func newTranscriptShareLinkTable(db *pgxpool.Pool) *TranscriptShareLinkTable {
+	return &TranscriptShareLinkTable{
+		db,
+	}
+}
+
+func (t TranscriptShareLinkTable) Schema() string {
+	return `
+CREATE TABLE IF NOT EXISTS transcript_share_links(
+	"id" uuid NOT NULL,
+	"guild_id" int8 NOT NULL,
+	"ticket_id" int4 NOT NULL,
+	"created_by" int8 NOT NULL,
+	"created_at" timestamptz NOT NULL,
+	"expires_at" timestamptz NOT NULL,
+	"single_use" bool NOT NULL,
+	"used_at" timestamptz DEFAULT NULL,
+	PRIMARY KEY("id")
+);
+CREATE INDEX IF NOT EXISTS transcript_share_links_guild_id ON transcript_share_links("guild_id");
+`
+}
+
+func (t *TranscriptShareLinkTable) Create(ctx context.Context, link TranscriptShareLink) error {
+	query := `
+INSERT INTO transcript_share_links("id", "guild_id", "ticket_id", "created_by", "created_at", "expires_at", "single_use")
+VALUES($1, $2, $3, $4, $5, $6, $7);
+`
+
+	_, err := t.Exec(ctx, query, link.Id, link.GuildId, link.TicketId, link.CreatedBy, link.CreatedAt, link.ExpiresAt, link.SingleUse)
+	return err
+}
+
+// GetByGuild returns the guild's links that have not expired, newest first
+func (t *TranscriptShareLinkTable) GetByGuild(ctx context.Context, guildId uint64) ([]TranscriptShareLink, error) {
+	query := `
+SELECT "id", "guild_id", "ticket_id", "created_by", "created_at", "expires_at", "single_use", "used_at"
+FROM transcript_share_links
+WHERE "guild_id" = $1 AND "expires_at" > NOW()
+ORDER BY "created_at" DESC;
+`
+
+	rows, err := t.Query(ctx, query, guildId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	links := make([]TranscriptShareLink, 0)
+	for rows.Next() {
+		var link TranscriptShareLink
+		if err := rows.Scan(
+			&link.Id,
+			&link.GuildId,
+			&link.TicketId,
+			&link.CreatedBy,
+			&link.CreatedAt,
+			&link.ExpiresAt,
+			&link.SingleUse,
+			&link.UsedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
+
+// Get returns a link by its ID, whether or not it can still be used
+func (t *TranscriptShareLinkTable) Get(ctx context.Context, id uuid.UUID) (TranscriptShareLink, bool, error) {
+	query := `
+SELECT "id", "guild_id", "ticket_id", "created_by", "created_at", "expires_at", "single_use", "used_at"
+FROM transcript_share_links
+WHERE "id" = $1;
+`
+
+	var link TranscriptShareLink
+	if err := t.QueryRow(ctx, query, id).Scan(
+		&link.Id,
+		&link.GuildId,
+		&link.TicketId,
+		&link.CreatedBy,
+		&link.CreatedAt,
+		&link.ExpiresAt,
+		&link.SingleUse,
+		&link.UsedAt,
+	); err != nil {
+		if err == pgx.ErrNoRows {
+			return TranscriptShareLink{}, false, nil
+		} else {
+			return TranscriptShareLink{}, false, err
+		}
+	}
+
+	return link, true, nil
+}
+
+// Usable returns whether the link has not expired, and is not a single-use link that has already been used. Use must
+// still be called to consume the link, as this check is not atomic.
+func (l TranscriptShareLink) Usable() bool {
+	return time.Now().Before(l.ExpiresAt) && (!l.SingleUse || l.UsedAt == nil)
+}
+
+// Use marks a link as used, returning false if it has been revoked, has expired, or is single-use and has already been
+// used. The check and update are a single statement, so a single-use link can not be opened twice concurrently.
+func (t *TranscriptShareLinkTable) Use(ctx context.Context, id uuid.UUID) (TranscriptShareLink, bool, error) {
+	query := `
+UPDATE transcript_share_links
+SET "used_at" = NOW()
+WHERE "id" = $1 AND "expires_at" > NOW() AND (NOT "single_use" OR "used_at" IS NULL)
+RETURNING "id", "guild_id", "ticket_id", "created_by", "created_at", "expires_at", "single_use", "used_at";
+`
+
+	var link TranscriptShareLink
+	if err := t.QueryRow(ctx, query, id).Scan(
+		&link.Id,
+		&link.GuildId,
+		&link.TicketId,
+		&link.CreatedBy,
+		&link.CreatedAt,
+		&link.ExpiresAt,
+		&link.SingleUse,
+		&link.UsedAt,
+	); err != nil {
+		if err == pgx.ErrNoRows {
+			return TranscriptShareLink{}, false, nil
+		} else {
+			return TranscriptShareLink{}, false, err
+		}
+	}
+
+	return link, true, nil
+}
+
+// Delete revokes a link. Returns false if the link did not exist in the guild.
+func (t *TranscriptShareLinkTable) Delete(ctx context.Context, guildId uint64, id uuid.UUID) (bool, error) {
+	query := `DELETE FROM transcript_share_links WHERE "guild_id" = $1 AND "id" = $2;`
+
+	res, err := t.Exec(ctx, query, guildId, id)
+	if err != nil {
+		return false, err
+	}
+
+	return res.RowsAffected() > 0, nil
+}
@@ -0,0 +1,179 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/TicketsBot-cloud/common/chatrelay"
+	"github.com/go-redis/redis/v8"
+	"github.com/rxdn/gdl/objects/channel/message"
+)
+
+const (
+	LiveChatHistorySize = 100
+	LiveChatHistoryTTL  = time.Hour
+
+	// LiveChatPatchTTL is how long the time of a ticket's last edit or deletion is kept for. A client whose last message
+	// is older than this can not be told whether it missed a patch.
+	LiveChatPatchTTL = 24 * time.Hour
+)
+
+// Milliseconds between the Unix epoch and the Discord epoch, which snowflake timestamps are relative to
+const discordEpoch = 1420070400000
+
+func liveChatHistoryKey(guildId uint64, ticketId int) string {
+	return fmt.Sprintf("tickets:livechat:history:%d:%d", guildId, ticketId)
+}
+
+func liveChatPatchKey(guildId uint64, ticketId int) string {
+	return fmt.Sprintf("tickets:livechat:patched:%d:%d", guildId, ticketId)
+}
+
+// Snowflakes do not fit in a float64, so score by their millisecond timestamp instead. Every API replica appends the
+// same relayed message, and as the encoded member is identical, ZADD keeps a single copy.
+func snowflakeScore(id uint64) float64 {
+	return float64(id >> 22)
+}
+
+// AppendLiveChatHistory stores a relayed message in the per-ticket ring buffer used to resume live-chat sessions.
+func (c *RedisClient) AppendLiveChatHistory(ctx context.Context, data chatrelay.MessageData) error {
+	encoded, err := json.Marshal(data.Message)
+	if err != nil {
+		return err
+	}
+
+	key := liveChatHistoryKey(data.Ticket.GuildId, data.Ticket.Id)
+
+	pipe := c.TxPipeline()
+	pipe.ZAdd(ctx, key, &redis.Z{
+		Score:  snowflakeScore(data.Message.Id),
+		Member: string(encoded),
+	})
+	pipe.ZRemRangeByRank(ctx, key, 0, -LiveChatHistorySize-1)
+	pipe.Expire(ctx, key, LiveChatHistoryTTL)
+
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// UpdateLiveChatHistory replaces a buffered message with its edited version, and records that the ticket was patched.
+// As with appends, every API replica applies the same update, which converges on a single copy of the new version.
+func (c *RedisClient) UpdateLiveChatHistory(ctx context.Context, data chatrelay.MessageData) error {
+	encoded, err := json.Marshal(data.Message)
+	if err != nil {
+		return err
+	}
+
+	return c.patchLiveChatHistory(ctx, data.Ticket.GuildId, data.Ticket.Id, data.Message.Id, &redis.Z{
+		Score:  snowflakeScore(data.Message.Id),
+		Member: string(encoded),
+	})
+}
+
+// DeleteLiveChatHistory removes a deleted message from the buffer, and records that the ticket was patched
+func (c *RedisClient) DeleteLiveChatHistory(ctx context.Context, guildId uint64, ticketId int, messageId uint64) error {
+	return c.patchLiveChatHistory(ctx, guildId, ticketId, messageId, nil)
+}
+
+// patchLiveChatHistory removes any buffered copies of a message, adding replacement in their place if it is non-nil
+func (c *RedisClient) patchLiveChatHistory(ctx context.Context, guildId uint64, ticketId int, messageId uint64, replacement *redis.Z) error {
+	key := liveChatHistoryKey(guildId, ticketId)
+	score := strconv.FormatFloat(snowflakeScore(messageId), 'f', -1, 64)
+
+	// Several messages may share a millisecond, so check the ID of each
+	candidates, err := c.ZRangeByScore(ctx, key, &redis.ZRangeBy{Min: score, Max: score}).Result()
+	if err != nil {
+		return err
+	}
+
+	var stale []interface{}
+	for _, member := range candidates {
+		var msg message.Message
+		if err := json.Unmarshal([]byte(member), &msg); err != nil {
+			return err
+		}
+
+		if msg.Id == messageId {
+			stale = append(stale, member)
+		}
+	}
+
+	pipe := c.TxPipeline()
+	pipe.Set(ctx, liveChatPatchKey(guildId, ticketId), time.Now().UnixMilli()-discordEpoch, LiveChatPatchTTL)
+
+	if len(stale) > 0 {
+		pipe.ZRem(ctx, key, stale...)
+
+		// Only replace messages that are still buffered, so that an edit can not resurrect an evicted message
+		if replacement != nil {
+			pipe.ZAdd(ctx, key, replacement)
+		}
+	}
+
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// MayHaveMissedLiveChatPatch returns whether a message sent before or at lastMessageId may have been edited or deleted
+// after lastMessageId was sent, in which case a resuming client must refetch the ticket, as such patches are not
+// replayed.
+func (c *RedisClient) MayHaveMissedLiveChatPatch(ctx context.Context, guildId uint64, ticketId int, lastMessageId uint64) (bool, error) {
+	lastMessageTime := int64(snowflakeScore(lastMessageId))
+
+	// The time of the last patch may have expired since
+	if time.Now().UnixMilli()-discordEpoch-lastMessageTime > LiveChatPatchTTL.Milliseconds() {
+		return true, nil
+	}
+
+	patchedAt, err := c.Get(ctx, liveChatPatchKey(guildId, ticketId)).Int64()
+	if err != nil {
+		if err == redis.Nil {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return patchedAt >= lastMessageTime, nil
+}
+
+// GetLiveChatHistorySince returns the buffered messages sent after lastMessageId, oldest first. The returned bool is
+// only true if the buffer reaches back to lastMessageId. Otherwise, messages may have been trimmed or expired from the
+// buffer, and the caller must check whether any were sent before relying on the replay.
+func (c *RedisClient) GetLiveChatHistorySince(ctx context.Context, guildId uint64, ticketId int, lastMessageId uint64) ([]message.Message, bool, error) {
+	key := liveChatHistoryKey(guildId, ticketId)
+
+	raw, err := c.ZRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, false, err
+	}
+
+	// An empty buffer may have expired, and a partial one may have been trimmed, so only an entry at or before the
+	// client's last message proves that nothing is missing
+	var complete bool
+
+	messages := make([]message.Message, 0, len(raw))
+	for _, member := range raw {
+		var msg message.Message
+		if err := json.Unmarshal([]byte(member), &msg); err != nil {
+			return nil, false, err
+		}
+
+		if msg.Id <= lastMessageId {
+			complete = true
+			continue
+		}
+
+		messages = append(messages, msg)
+	}
+
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].Id < messages[j].Id
+	})
+
+	return messages, complete, nil
+}
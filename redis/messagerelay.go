@@ -0,0 +1,46 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/TicketsBot-cloud/common/chatrelay"
+	"github.com/TicketsBot-cloud/database"
+)
+
+// The worker relays new messages over chatrelay, but does not yet relay edits or deletions. These channels are the
+// contract the dashboard expects for them: an edit is published as a chatrelay.MessageData holding the edited message,
+// and a deletion as a MessageDeleteData. They belong alongside chatrelay in the common module, and should be moved
+// there once the worker publishes them. Until then, nothing listens on them unless config.Conf.Bot.ChatRelayPatches is
+// set.
+const (
+	messageUpdateChannel = "tickets:chatrelay:update"
+	messageDeleteChannel = "tickets:chatrelay:delete"
+)
+
+type MessageDeleteData struct {
+	Ticket    database.Ticket `json:"ticket"`
+	MessageId uint64          `json:"message_id"`
+}
+
+func (c *RedisClient) ListenMessageUpdates(ch chan chatrelay.MessageData) {
+	for payload := range c.Subscribe(context.Background(), messageUpdateChannel).Channel() {
+		var data chatrelay.MessageData
+		if err := json.Unmarshal([]byte(payload.Payload), &data); err != nil {
+			continue
+		}
+
+		ch <- data
+	}
+}
+
+func (c *RedisClient) ListenMessageDeletes(ch chan MessageDeleteData) {
+	for payload := range c.Subscribe(context.Background(), messageDeleteChannel).Channel() {
+		var data MessageDeleteData
+		if err := json.Unmarshal([]byte(payload.Payload), &data); err != nil {
+			continue
+		}
+
+		ch <- data
+	}
+}
@@ -0,0 +1,36 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// PermissionRevalidation requests that open live-chat sockets re-check their permissions immediately, rather than
+// waiting for the next periodic check. If UserId is nil, every socket in the guild is re-checked, e.g. when a role
+// is removed from a team.
+type PermissionRevalidation struct {
+	GuildId uint64  `json:"guild_id"`
+	UserId  *uint64 `json:"user_id,omitempty"`
+}
+
+const permissionRevalidationChannel = "tickets:livechat:revalidate"
+
+func (c *RedisClient) PublishPermissionRevalidation(ctx context.Context, data PermissionRevalidation) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	return c.Publish(ctx, permissionRevalidationChannel, string(encoded)).Err()
+}
+
+func (c *RedisClient) ListenPermissionRevalidations(ch chan PermissionRevalidation) {
+	for payload := range c.Subscribe(context.Background(), permissionRevalidationChannel).Channel() {
+		var data PermissionRevalidation
+		if err := json.Unmarshal([]byte(payload.Payload), &data); err != nil {
+			continue
+		}
+
+		ch <- data
+	}
+}
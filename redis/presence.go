@@ -0,0 +1,140 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// TicketPresenceTTL is how long a viewer is listed without refreshing their presence. Viewers refresh well within
+// this window, so that a crashed API replica does not leave viewers listed for long.
+const TicketPresenceTTL = 90 * time.Second
+
+type PresenceEventType string
+
+const (
+	PresenceEventViewers PresenceEventType = "viewers"
+	PresenceEventTyping  PresenceEventType = "typing"
+)
+
+// PresenceEvent is relayed between API replicas, so that viewers of a ticket connected to different replicas can see
+// each other. Viewers is populated for PresenceEventViewers, and UserId for PresenceEventTyping.
+type PresenceEvent struct {
+	Type     PresenceEventType `json:"type"`
+	GuildId  uint64            `json:"guild_id"`
+	TicketId int               `json:"ticket_id"`
+	UserId   uint64            `json:"user_id,omitempty"`
+	Viewers  []uint64          `json:"viewers,omitempty"`
+}
+
+const presenceEventChannel = "tickets:livechat:presence"
+
+func ticketPresenceKey(guildId uint64, ticketId int) string {
+	return fmt.Sprintf("tickets:livechat:viewers:%d:%d", guildId, ticketId)
+}
+
+// A user may view the same ticket from multiple tabs, so each connection is stored separately
+func presenceMember(userId uint64, sessionId string) string {
+	return fmt.Sprintf("%d:%s", userId, sessionId)
+}
+
+// SetTicketPresence adds or refreshes a viewer of a ticket.
+func (c *RedisClient) SetTicketPresence(ctx context.Context, guildId uint64, ticketId int, userId uint64, sessionId string) error {
+	key := ticketPresenceKey(guildId, ticketId)
+	now := time.Now()
+
+	pipe := c.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Unix(), 10))
+	pipe.ZAdd(ctx, key, &redis.Z{
+		Score:  float64(now.Add(TicketPresenceTTL).Unix()),
+		Member: presenceMember(userId, sessionId),
+	})
+	pipe.Expire(ctx, key, TicketPresenceTTL)
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (c *RedisClient) RemoveTicketPresence(ctx context.Context, guildId uint64, ticketId int, userId uint64, sessionId string) error {
+	return c.ZRem(ctx, ticketPresenceKey(guildId, ticketId), presenceMember(userId, sessionId)).Err()
+}
+
+// GetTicketViewers returns the IDs of the users currently viewing a ticket.
+func (c *RedisClient) GetTicketViewers(ctx context.Context, guildId uint64, ticketId int) ([]uint64, error) {
+	viewers, err := c.GetTicketsViewers(ctx, guildId, []int{ticketId})
+	if err != nil {
+		return nil, err
+	}
+
+	return viewers[ticketId], nil
+}
+
+// GetTicketsViewers returns the IDs of the users currently viewing each ticket. Tickets without viewers are omitted.
+func (c *RedisClient) GetTicketsViewers(ctx context.Context, guildId uint64, ticketIds []int) (map[int][]uint64, error) {
+	if len(ticketIds) == 0 {
+		return map[int][]uint64{}, nil
+	}
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+
+	pipe := c.Pipeline()
+	cmds := make(map[int]*redis.StringSliceCmd, len(ticketIds))
+	for _, ticketId := range ticketIds {
+		cmds[ticketId] = pipe.ZRangeByScore(ctx, ticketPresenceKey(guildId, ticketId), &redis.ZRangeBy{
+			Min: "(" + now,
+			Max: "+inf",
+		})
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	viewers := make(map[int][]uint64)
+	for ticketId, cmd := range cmds {
+		members, err := cmd.Result()
+		if err != nil {
+			return nil, err
+		}
+
+		seen := make(map[uint64]struct{})
+		for _, member := range members {
+			userId, err := strconv.ParseUint(strings.SplitN(member, ":", 2)[0], 10, 64)
+			if err != nil {
+				continue
+			}
+
+			if _, ok := seen[userId]; !ok {
+				seen[userId] = struct{}{}
+				viewers[ticketId] = append(viewers[ticketId], userId)
+			}
+		}
+	}
+
+	return viewers, nil
+}
+
+func (c *RedisClient) PublishPresenceEvent(ctx context.Context, event PresenceEvent) error {
+	encoded, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return c.Publish(ctx, presenceEventChannel, string(encoded)).Err()
+}
+
+func (c *RedisClient) ListenPresenceEvents(ch chan PresenceEvent) {
+	for payload := range c.Subscribe(context.Background(), presenceEventChannel).Channel() {
+		var event PresenceEvent
+		if err := json.Unmarshal([]byte(payload.Payload), &event); err != nil {
+			continue
+		}
+
+		ch <- event
+	}
+}
@@ -0,0 +1,52 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+type TicketEventType string
+
+const (
+	TicketEventClosed    TicketEventType = "closed"
+	TicketEventClaimed   TicketEventType = "claimed"
+	TicketEventUnclaimed TicketEventType = "unclaimed"
+)
+
+// TicketEvent is published on tickets:ticketevents whenever a ticket is claimed, unclaimed, transferred or closed from
+// the dashboard. The worker does not publish ticket events: tickets opened from Discord or the dashboard are announced
+// to live ticket lists from the first message relayed over chatrelay instead, and tickets closed from Discord appear on
+// the next ticket list fetch.
+type TicketEvent struct {
+	Type      TicketEventType `json:"type"`
+	GuildId   uint64          `json:"guild_id"`
+	TicketId  int             `json:"ticket_id"`
+	UserId    uint64          `json:"user_id"`
+	PanelId   *int            `json:"panel_id,omitempty"`
+	ClaimedBy *uint64         `json:"claimed_by,omitempty"`
+	ActorId   *uint64         `json:"actor_id,omitempty"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+const ticketEventChannel = "tickets:ticketevents"
+
+func (c *RedisClient) PublishTicketEvent(ctx context.Context, event TicketEvent) error {
+	encoded, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return c.Publish(ctx, ticketEventChannel, string(encoded)).Err()
+}
+
+func (c *RedisClient) ListenTicketEvents(ch chan TicketEvent) {
+	for payload := range c.Subscribe(context.Background(), ticketEventChannel).Channel() {
+		var event TicketEvent
+		if err := json.Unmarshal([]byte(payload.Payload), &event); err != nil {
+			continue
+		}
+
+		ch <- event
+	}
+}
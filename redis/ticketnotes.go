@@ -0,0 +1,45 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+
+	dbclient "github.com/TicketsBot-cloud/dashboard/database"
+)
+
+type TicketNoteEventType string
+
+const (
+	TicketNoteCreated TicketNoteEventType = "created"
+	TicketNoteUpdated TicketNoteEventType = "updated"
+	TicketNoteDeleted TicketNoteEventType = "deleted"
+)
+
+// TicketNoteEvent is relayed between API replicas, so that staff viewing a ticket see notes as they are left. Only the
+// ID of the note is populated for TicketNoteDeleted.
+type TicketNoteEvent struct {
+	Type TicketNoteEventType `json:"type"`
+	Note dbclient.TicketNote `json:"note"`
+}
+
+const ticketNoteEventChannel = "tickets:livechat:notes"
+
+func (c *RedisClient) PublishTicketNoteEvent(ctx context.Context, event TicketNoteEvent) error {
+	encoded, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return c.Publish(ctx, ticketNoteEventChannel, string(encoded)).Err()
+}
+
+func (c *RedisClient) ListenTicketNoteEvents(ch chan TicketNoteEvent) {
+	for payload := range c.Subscribe(context.Background(), ticketNoteEventChannel).Channel() {
+		var event TicketNoteEvent
+		if err := json.Unmarshal([]byte(payload.Payload), &event); err != nil {
+			continue
+		}
+
+		ch <- event
+	}
+}
@@ -0,0 +1,31 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// TicketOpenRequest asks the worker to open a ticket against a panel on behalf of a member. The dashboard has already
+// checked the ticket limit and blacklists, and taken a ticket rate limit token.
+type TicketOpenRequest struct {
+	GuildId  uint64 `json:"guild_id"`
+	PanelId  int    `json:"panel_id"`
+	UserId   uint64 `json:"user_id"`
+	OpenedBy uint64 `json:"opened_by"`
+	// FormData maps form input custom IDs to pre-filled answers
+	FormData map[string]string `json:"form_data,omitempty"`
+}
+
+const ticketOpenKey = "tickets:open"
+
+// PublishTicketOpen queues the request for the worker. Requests are pushed onto a list, in the same way as closerelay
+// queues ticket closes, so that exactly one worker picks each up. Nothing consumes the list unless the worker has been
+// deployed with support for it, so callers must check config.Conf.Bot.TicketOpenRelay first.
+func (c *RedisClient) PublishTicketOpen(ctx context.Context, request TicketOpenRequest) error {
+	encoded, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	return c.RPush(ctx, ticketOpenKey, string(encoded)).Err()
+}
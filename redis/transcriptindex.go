@@ -0,0 +1,32 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const TranscriptIndexLockDuration = 10 * time.Minute
+
+// TakeTranscriptIndexLock ensures only a single API replica indexes each closed ticket, as they all receive the event.
+func (c *RedisClient) TakeTranscriptIndexLock(ctx context.Context, guildId uint64, ticketId int) (bool, error) {
+	key := fmt.Sprintf("tickets:transcriptindex:%d:%d", guildId, ticketId)
+
+	res, err := c.SetNX(ctx, key, "1", TranscriptIndexLockDuration).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return res, nil
+}
+
+// TakeTranscriptIndexSweepLock ensures only a single API replica sweeps for unindexed transcripts in each interval. The
+// lock is not released once the sweep completes, so that the other replicas skip the interval.
+func (c *RedisClient) TakeTranscriptIndexSweepLock(ctx context.Context, interval time.Duration) (bool, error) {
+	res, err := c.SetNX(ctx, "tickets:transcriptindexsweep", "1", interval).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return res, nil
+}
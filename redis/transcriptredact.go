@@ -0,0 +1,28 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const TranscriptRedactLockDuration = time.Minute
+
+// TakeTranscriptRedactLock prevents concurrent redactions of the same transcript, as each rewrites the whole stored
+// transcript, and so would otherwise overwrite the other's changes.
+func (c *RedisClient) TakeTranscriptRedactLock(ctx context.Context, guildId uint64, ticketId int) (bool, error) {
+	res, err := c.SetNX(ctx, transcriptRedactLockKey(guildId, ticketId), "1", TranscriptRedactLockDuration).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return res, nil
+}
+
+func (c *RedisClient) ReleaseTranscriptRedactLock(ctx context.Context, guildId uint64, ticketId int) error {
+	return c.Del(ctx, transcriptRedactLockKey(guildId, ticketId)).Err()
+}
+
+func transcriptRedactLockKey(guildId uint64, ticketId int) string {
+	return fmt.Sprintf("tickets:transcriptredact:%d:%d", guildId, ticketId)
+}
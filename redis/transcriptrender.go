@@ -0,0 +1,83 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// TranscriptRenderCacheDuration bounds how long a stale render can be served after a change that is not explicitly
+// invalidated, such as a renamed channel or role, or an update to the render service.
+const TranscriptRenderCacheDuration = 24 * time.Hour
+
+// Renders are cached under the transcript's current generation, which InvalidateTranscriptRender replaces. A render of
+// the transcript from before a redaction is therefore written under the old generation, and can never be served, even
+// if it finishes after the invalidation. The generation must outlive any render cached under the previous one.
+const transcriptRenderGenerationDuration = 2 * TranscriptRenderCacheDuration
+
+// GetTranscriptRenderGeneration returns the generation to cache a render under. It must be fetched before the
+// transcript itself, so that a concurrent redaction is guaranteed to invalidate the render.
+func (c *RedisClient) GetTranscriptRenderGeneration(ctx context.Context, guildId uint64, ticketId int) (int64, error) {
+	generation, err := c.Get(ctx, transcriptRenderGenerationKey(guildId, ticketId)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+
+	return generation, err
+}
+
+// GetTranscriptRender returns the cached render of a transcript, produced by the first of the renderer versions given
+// that has one.
+func (c *RedisClient) GetTranscriptRender(ctx context.Context, guildId uint64, ticketId int, generation int64, versions []string) ([]byte, bool, error) {
+	res, err := c.HMGet(ctx, transcriptRenderKey(guildId, ticketId, generation), versions...).Result()
+	if err != nil {
+		return nil, false, err
+	}
+
+	for _, value := range res {
+		if html, ok := value.(string); ok {
+			return []byte(html), true, nil
+		}
+	}
+
+	return nil, false, nil
+}
+
+func (c *RedisClient) SetTranscriptRender(ctx context.Context, guildId uint64, ticketId int, generation int64, version string, html []byte) error {
+	key := transcriptRenderKey(guildId, ticketId, generation)
+
+	pipe := c.TxPipeline()
+	pipe.HSet(ctx, key, version, html)
+	pipe.Expire(ctx, key, TranscriptRenderCacheDuration)
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// InvalidateTranscriptRender moves the transcript on to a new generation, so that no render cached before now, or
+// from a copy of the transcript fetched before now, is served again. The current generation's renders are removed to
+// free memory. The new generation is a timestamp, rather than a counter, so that it cannot repeat an old generation
+// once the key expires.
+func (c *RedisClient) InvalidateTranscriptRender(ctx context.Context, guildId uint64, ticketId int) error {
+	generation, err := c.GetTranscriptRenderGeneration(ctx, guildId, ticketId)
+	if err != nil {
+		return err
+	}
+
+	pipe := c.TxPipeline()
+	pipe.Set(ctx, transcriptRenderGenerationKey(guildId, ticketId), time.Now().UnixNano(), transcriptRenderGenerationDuration)
+	pipe.Del(ctx, transcriptRenderKey(guildId, ticketId, generation))
+
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func transcriptRenderKey(guildId uint64, ticketId int, generation int64) string {
+	return fmt.Sprintf("tickets:transcriptrender:%d:%d:%d", guildId, ticketId, generation)
+}
+
+func transcriptRenderGenerationKey(guildId uint64, ticketId int) string {
+	return fmt.Sprintf("tickets:transcriptrender:generation:%d:%d", guildId, ticketId)
+}
@@ -0,0 +1,17 @@
+package redis
+
+import (
+	"context"
+	"time"
+)
+
+// TakeTranscriptRetentionLock ensures only a single API replica purges expired transcripts in each interval. The lock
+// is not released once the purge completes, so that the other replicas skip the interval.
+func (c *RedisClient) TakeTranscriptRetentionLock(ctx context.Context, interval time.Duration) (bool, error) {
+	res, err := c.SetNX(ctx, "tickets:transcriptretention", "1", interval).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return res, nil
+}
@@ -1,9 +1,11 @@
 package rpc
 
 import (
+	"context"
 	"fmt"
-	"github.com/go-redis/redis"
 	"time"
+
+	"github.com/go-redis/redis/v8"
 )
 
 var script = redis.NewScript(`
@@ -33,10 +35,12 @@ return success
 var TicketOpenLimit = 10
 var TicketOpenLimitInterval = time.Second * 30
 
-func TakeTicketRateLimitToken(client *redis.Client, guildId uint64) (bool, error) {
+// TakeTicketRateLimitToken shares its bucket with the worker, so tickets opened from the dashboard count towards the
+// same per-guild limit as tickets opened from Discord.
+func TakeTicketRateLimitToken(ctx context.Context, client *redis.Client, guildId uint64) (bool, error) {
 	key := fmt.Sprintf("tickets:openratelimit:%d", guildId)
 
-	res, err := script.Run(client, []string{key}, TicketOpenLimit, TicketOpenLimitInterval.Seconds()).Result()
+	res, err := script.Run(ctx, client, []string{key}, TicketOpenLimit, TicketOpenLimitInterval.Seconds()).Result()
 	if err != nil {
 		return false, err
 	}
@@ -3,3 +3,7 @@ package utils
 import "github.com/TicketsBot-cloud/archiverclient"
 
 var ArchiverClient *archiverclient.ArchiverClient
+
+// ArchiverRetriever is the retriever backing ArchiverClient, which is needed to delete transcripts, as the client does
+// not expose deletion itself
+var ArchiverRetriever archiverclient.Retriever
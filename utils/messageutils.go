@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/TicketsBot-cloud/dashboard/botcontext"
+	dbclient "github.com/TicketsBot-cloud/dashboard/database"
+	"github.com/TicketsBot-cloud/dashboard/internal/api"
+	"github.com/TicketsBot-cloud/database"
+	"github.com/rxdn/gdl/objects/channel/embed"
+	"github.com/rxdn/gdl/rest"
+	"github.com/rxdn/gdl/rest/request"
+)
+
+// DashboardMessage is a reply sent to a ticket by a staff member using the dashboard
+type DashboardMessage struct {
+	Content     string
+	Embeds      []*embed.Embed
+	Attachments []DashboardAttachment
+}
+
+// DashboardAttachment is a file uploaded with a DashboardMessage. The contents are held in memory, as the upload may
+// need to be sent twice if the ticket webhook fails.
+type DashboardAttachment struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+// buildAttachments returns the message's attachments with fresh readers, for a single request to Discord
+func (m DashboardMessage) buildAttachments() []request.Attachment {
+	if len(m.Attachments) == 0 {
+		return nil
+	}
+
+	attachments := make([]request.Attachment, len(m.Attachments))
+	for i, attachment := range m.Attachments {
+		attachments[i] = request.Attachment{
+			Id:       i,
+			FileName: attachment.FileName,
+			File: request.File{
+				ContentType: attachment.ContentType,
+				Reader:      bytes.NewReader(attachment.Data),
+			},
+		}
+	}
+
+	return attachments
+}
+
+// SendDashboardMessage sends a message to the ticket channel on behalf of a staff member using the dashboard. The
+// ticket webhook is preferred, falling back to sending the message as the bot if the webhook is missing or broken.
+func SendDashboardMessage(ctx context.Context, botContext *botcontext.BotContext, ticket database.Ticket, userId uint64, msg DashboardMessage) *api.RequestError {
+	content := msg.Content
+	if len(content) > 2000 {
+		content = content[0:1999]
+	}
+
+	// Preferably send via a webhook
+	webhook, err := dbclient.Client.Webhooks.Get(ctx, ticket.GuildId, ticket.Id)
+	if err != nil {
+		return api.NewError(http.StatusInternalServerError, err)
+	}
+
+	settings, err := dbclient.Client.Settings.Get(ctx, ticket.GuildId)
+	if err != nil {
+		return api.NewInternalServerError(err, "Failed to fetch settings")
+	}
+
+	if webhook.Id != 0 {
+		var webhookData rest.WebhookBody
+		if settings.AnonymiseDashboardResponses {
+			guild, err := botContext.GetGuild(ctx, ticket.GuildId)
+			if err != nil {
+				return api.NewInternalServerError(err, "Failed to fetch guild")
+			}
+
+			webhookData = rest.WebhookBody{
+				Content:     content,
+				Embeds:      msg.Embeds,
+				Attachments: msg.buildAttachments(),
+				Username:    guild.Name,
+				AvatarUrl:   guild.IconUrl(),
+			}
+		} else {
+			user, err := botContext.GetUser(ctx, userId)
+			if err != nil {
+				return api.NewInternalServerError(err, "Failed to fetch user")
+			}
+
+			webhookData = rest.WebhookBody{
+				Content:     content,
+				Embeds:      msg.Embeds,
+				Attachments: msg.buildAttachments(),
+				Username:    user.EffectiveName(),
+				AvatarUrl:   user.AvatarUrl(256),
+			}
+		}
+
+		// TODO: Ratelimit
+		_, err = rest.ExecuteWebhook(ctx, webhook.Token, nil, webhook.Id, true, webhookData)
+		if err == nil {
+			return nil
+		}
+
+		// We can delete the webhook in this case
+		var unwrapped request.RestError
+		if errors.As(err, &unwrapped); unwrapped.StatusCode == 403 || unwrapped.StatusCode == 404 {
+			go dbclient.Client.Webhooks.Delete(context.Background(), ticket.GuildId, ticket.Id)
+		} else if unwrapped.StatusCode == http.StatusRequestEntityTooLarge {
+			// The bot is subject to the same upload limit, so there is no point falling back
+			return attachmentsTooLargeError(err)
+		}
+	}
+
+	message := content
+	if !settings.AnonymiseDashboardResponses {
+		user, err := botContext.GetUser(ctx, userId)
+		if err != nil {
+			return api.NewInternalServerError(err, "Failed to fetch user")
+		}
+
+		message = fmt.Sprintf("**%s**: %s", user.EffectiveName(), message)
+	}
+
+	if len(message) > 2000 {
+		message = message[0:1999]
+	}
+
+	if ticket.ChannelId == nil {
+		return api.NewErrorWithMessage(http.StatusNotFound, errors.New("ticket channel ID is nil"), "Ticket channel ID is nil")
+	}
+
+	data := rest.CreateMessageData{
+		Content:     message,
+		Embeds:      msg.Embeds,
+		Attachments: msg.buildAttachments(),
+	}
+
+	if _, err := rest.CreateMessage(ctx, botContext.Token, botContext.RateLimiter, *ticket.ChannelId, data); err != nil {
+		var unwrapped request.RestError
+		if errors.As(err, &unwrapped) && unwrapped.StatusCode == http.StatusRequestEntityTooLarge {
+			return attachmentsTooLargeError(err)
+		}
+
+		return api.NewError(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+func attachmentsTooLargeError(err error) *api.RequestError {
+	return api.NewErrorWithMessage(http.StatusRequestEntityTooLarge, err, "Your attachments exceed the upload limit for this server")
+}
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"context"
+	"errors"
+
+	"github.com/TicketsBot-cloud/dashboard/botcontext"
+	dbclient "github.com/TicketsBot-cloud/dashboard/database"
+	"github.com/TicketsBot-cloud/database"
+	"github.com/TicketsBot/worker/bot/customisation"
+	"github.com/rxdn/gdl/objects/channel"
+	"github.com/rxdn/gdl/objects/channel/embed"
+	"github.com/rxdn/gdl/permission"
+	"github.com/rxdn/gdl/rest"
+)
+
+var ErrTicketChannelMissing = errors.New("ticket channel ID is nil")
+
+// ClaimTicket assigns the ticket to the given staff member, restricting the channel's permissions according to the
+// guild's claim settings. Participants added to the ticket keep their access. Claiming an already claimed ticket
+// transfers it to the new claimer.
+func ClaimTicket(ctx context.Context, botContext *botcontext.BotContext, ticket database.Ticket, claimerId uint64) error {
+	if ticket.ChannelId == nil {
+		return ErrTicketChannelMissing
+	}
+
+	if err := dbclient.Client.TicketClaims.Set(ctx, ticket.GuildId, ticket.Id, claimerId); err != nil {
+		return err
+	}
+
+	overwrites, err := GenerateClaimedOverwrites(ctx, botContext, ticket, claimerId)
+	if err != nil {
+		return err
+	}
+
+	// If overwrites = nil, no changes to permissions should be made
+	if overwrites == nil {
+		return nil
+	}
+
+	ch, err := rest.GetChannel(ctx, botContext.Token, botContext.RateLimiter, *ticket.ChannelId)
+	if err != nil {
+		return err
+	}
+
+	overwrites, err = withParticipantOverwrites(ctx, ticket, overwrites, ch.PermissionOverwrites)
+	if err != nil {
+		return err
+	}
+
+	data := rest.ModifyChannelData{
+		PermissionOverwrites: overwrites,
+	}
+
+	_, err = rest.ModifyChannel(ctx, botContext.Token, botContext.RateLimiter, *ticket.ChannelId, data)
+	return err
+}
+
+// UnclaimTicket removes the ticket's claim, restoring access for every support representative assigned to it. Participants
+// added to the ticket keep their access.
+func UnclaimTicket(ctx context.Context, botContext *botcontext.BotContext, ticket database.Ticket) error {
+	if ticket.ChannelId == nil {
+		return ErrTicketChannelMissing
+	}
+
+	if err := dbclient.Client.TicketClaims.Delete(ctx, ticket.GuildId, ticket.Id); err != nil {
+		return err
+	}
+
+	ch, err := rest.GetChannel(ctx, botContext.Token, botContext.RateLimiter, *ticket.ChannelId)
+	if err != nil {
+		return err
+	}
+
+	integrationRoleId, err := GetIntegrationRoleId(ctx, botContext, ticket.GuildId)
+	if err != nil {
+		return err
+	}
+
+	// The bot is granted ManageWebhooks when the ticket is opened if it has the permission guild-wide, which we cannot
+	// see from here, so carry it over from the channel's current overwrites
+	selfAllow := permission.BuildPermissions(standardPermissions[:]...)
+	for _, overwrite := range ch.PermissionOverwrites {
+		isSelf := (overwrite.Type == channel.PermissionTypeMember && overwrite.Id == botContext.BotId) ||
+			(overwrite.Type == channel.PermissionTypeRole && integrationRoleId != nil && overwrite.Id == *integrationRoleId)
+
+		if isSelf && permission.HasPermissionRaw(overwrite.Allow, permission.ManageWebhooks) {
+			selfAllow |= permission.BuildPermissions(permission.ManageWebhooks)
+		}
+	}
+
+	overwrites, err := GenerateTicketOverwrites(ctx, botContext, ticket, selfAllow)
+	if err != nil {
+		return err
+	}
+
+	overwrites, err = withParticipantOverwrites(ctx, ticket, overwrites, ch.PermissionOverwrites)
+	if err != nil {
+		return err
+	}
+
+	data := rest.ModifyChannelData{
+		PermissionOverwrites: overwrites,
+	}
+
+	_, err = rest.ModifyChannel(ctx, botContext.Token, botContext.RateLimiter, *ticket.ChannelId, data)
+	return err
+}
+
+// SendTicketNotice posts an embed to the ticket channel, using the guild's custom success colour if it has one
+func SendTicketNotice(ctx context.Context, botContext *botcontext.BotContext, ticket database.Ticket, title, description string) error {
+	if ticket.ChannelId == nil {
+		return ErrTicketChannelMissing
+	}
+
+	colour, ok, err := dbclient.Client.CustomColours.Get(ctx, ticket.GuildId, int16(customisation.Green))
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		colour = customisation.DefaultColours[customisation.Green]
+	}
+
+	e := embed.NewEmbed().
+		SetTitle(title).
+		SetDescription(description).
+		SetColor(colour)
+
+	data := rest.CreateMessageData{
+		Embeds: Slice(e),
+	}
+
+	_, err = rest.CreateMessage(ctx, botContext.Token, botContext.RateLimiter, *ticket.ChannelId, data)
+	return err
+}
@@ -0,0 +1,324 @@
+package utils
+
+import (
+	"context"
+
+	"github.com/TicketsBot-cloud/dashboard/botcontext"
+	dbclient "github.com/TicketsBot-cloud/dashboard/database"
+	"github.com/TicketsBot-cloud/database"
+	"github.com/rxdn/gdl/objects/channel"
+	"github.com/rxdn/gdl/permission"
+)
+
+// The overwrites built here mirror those the bot applies when a ticket is opened, claimed or unclaimed, so that a
+// ticket claimed from the dashboard is indistinguishable from one claimed with /claim.
+
+// standardPermissions are the permissions that staff and the bot are given in a ticket
+var standardPermissions = [...]permission.Permission{
+	permission.ViewChannel,
+	permission.SendMessages,
+	permission.AddReactions,
+	permission.AttachFiles,
+	permission.ReadMessageHistory,
+	permission.EmbedLinks,
+	permission.UseApplicationCommands,
+}
+
+// minimalPermissions are the permissions that the ticket opener is always given, regardless of the guild's settings
+var minimalPermissions = [...]permission.Permission{
+	permission.ViewChannel,
+	permission.SendMessages,
+	permission.ReadMessageHistory,
+	permission.UseApplicationCommands,
+}
+
+var (
+	readOnlyAllowed = []permission.Permission{permission.ViewChannel, permission.ReadMessageHistory}
+	readOnlyDenied  = []permission.Permission{permission.SendMessages, permission.AddReactions}
+)
+
+func buildUserOverwrite(userId uint64, additionalPermissions database.TicketPermissions) channel.PermissionOverwrite {
+	return buildParticipantOverwrite(userId, channel.PermissionTypeMember, additionalPermissions)
+}
+
+// buildParticipantOverwrite builds the overwrite given to the ticket opener and anyone added to the ticket, respecting
+// the guild's settings for attachments, embeds and reactions
+func buildParticipantOverwrite(id uint64, overwriteType channel.PermissionOverwriteType, additionalPermissions database.TicketPermissions) channel.PermissionOverwrite {
+	allow := make([]permission.Permission, len(minimalPermissions), len(minimalPermissions)+3)
+	copy(allow, minimalPermissions[:]) // Do not append to minimalPermissions
+
+	var deny []permission.Permission
+
+	if additionalPermissions.AttachFiles {
+		allow = append(allow, permission.AttachFiles)
+	} else {
+		deny = append(deny, permission.AttachFiles)
+	}
+
+	if additionalPermissions.EmbedLinks {
+		allow = append(allow, permission.EmbedLinks)
+	} else {
+		deny = append(deny, permission.EmbedLinks)
+	}
+
+	if additionalPermissions.AddReactions {
+		allow = append(allow, permission.AddReactions)
+	} else {
+		deny = append(deny, permission.AddReactions)
+	}
+
+	return channel.PermissionOverwrite{
+		Id:    id,
+		Type:  overwriteType,
+		Allow: permission.BuildPermissions(allow...),
+		Deny:  permission.BuildPermissions(deny...),
+	}
+}
+
+func standardOverwrite(id uint64, overwriteType channel.PermissionOverwriteType) channel.PermissionOverwrite {
+	return channel.PermissionOverwrite{
+		Id:    id,
+		Type:  overwriteType,
+		Allow: permission.BuildPermissions(standardPermissions[:]...),
+		Deny:  0,
+	}
+}
+
+func everyoneOverwrite(guildId uint64) channel.PermissionOverwrite {
+	return channel.PermissionOverwrite{
+		Id:    guildId,
+		Type:  channel.PermissionTypeRole,
+		Allow: 0,
+		Deny:  permission.BuildPermissions(permission.ViewChannel),
+	}
+}
+
+// GetIntegrationRoleId returns the ID of the role Discord manages for the bot, or nil if it does not have one. The bot
+// is granted access to tickets through this role where possible, rather than through a member overwrite.
+func GetIntegrationRoleId(ctx context.Context, botContext *botcontext.BotContext, guildId uint64) (*uint64, error) {
+	roles, err := botContext.GetGuildRoles(ctx, guildId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, role := range roles {
+		if role.Tags.BotId != nil && *role.Tags.BotId == botContext.BotId {
+			return &role.Id, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// GenerateClaimedOverwrites builds the overwrites for a ticket once it has been claimed, according to the guild's
+// claim settings. If support representatives can still both view and type, returns (nil, nil), as no changes to the
+// channel's permissions should be made.
+func GenerateClaimedOverwrites(ctx context.Context, botContext *botcontext.BotContext, ticket database.Ticket, claimerId uint64) ([]channel.PermissionOverwrite, error) {
+	claimSettings, err := dbclient.Client.ClaimSettings.Get(ctx, ticket.GuildId)
+	if err != nil {
+		return nil, err
+	}
+
+	if claimSettings.SupportCanView && claimSettings.SupportCanType {
+		return nil, nil
+	}
+
+	adminUsers, err := dbclient.Client.Permissions.GetAdmins(ctx, ticket.GuildId)
+	if err != nil {
+		return nil, err
+	}
+
+	adminRoles, err := dbclient.Client.RolePermissions.GetAdminRoles(ctx, ticket.GuildId)
+	if err != nil {
+		return nil, err
+	}
+
+	additionalPermissions, err := dbclient.Client.TicketPermissions.Get(ctx, ticket.GuildId)
+	if err != nil {
+		return nil, err
+	}
+
+	integrationRoleId, err := GetIntegrationRoleId(ctx, botContext, ticket.GuildId)
+	if err != nil {
+		return nil, err
+	}
+
+	overwrites := []channel.PermissionOverwrite{
+		buildUserOverwrite(ticket.UserId, additionalPermissions),
+		everyoneOverwrite(ticket.GuildId),
+	}
+
+	// Admins and the claimer keep full access, as does the bot
+	adminUserTargets := append(append([]uint64{}, adminUsers...), claimerId)
+	adminRoleTargets := append([]uint64{}, adminRoles...)
+
+	if integrationRoleId == nil {
+		adminUserTargets = append(adminUserTargets, botContext.BotId)
+	} else {
+		adminRoleTargets = append(adminRoleTargets, *integrationRoleId)
+	}
+
+	for _, userId := range adminUserTargets {
+		overwrites = append(overwrites, standardOverwrite(userId, channel.PermissionTypeMember))
+	}
+
+	for _, roleId := range adminRoleTargets {
+		overwrites = append(overwrites, standardOverwrite(roleId, channel.PermissionTypeRole))
+	}
+
+	// Support can't view the ticket, and therefore can't type either
+	if !claimSettings.SupportCanView {
+		return overwrites, nil
+	}
+
+	// Support can view the ticket, but can't type
+	supportUsers, err := dbclient.Client.Permissions.GetSupportOnly(ctx, ticket.GuildId)
+	if err != nil {
+		return nil, err
+	}
+
+	supportRoles, err := dbclient.Client.RolePermissions.GetSupportRolesOnly(ctx, ticket.GuildId)
+	if err != nil {
+		return nil, err
+	}
+
+	if ticket.PanelId != nil {
+		teamUsers, err := dbclient.Client.SupportTeamMembers.GetAllSupportMembersForPanel(ctx, *ticket.PanelId)
+		if err != nil {
+			return nil, err
+		}
+
+		teamRoles, err := dbclient.Client.SupportTeamRoles.GetAllSupportRolesForPanel(ctx, *ticket.PanelId)
+		if err != nil {
+			return nil, err
+		}
+
+		supportUsers = append(supportUsers, teamUsers...)
+		supportRoles = append(supportRoles, teamRoles...)
+	}
+
+	for _, userId := range supportUsers {
+		// Support teams can include admins, who must not lose access
+		if Contains(adminUserTargets, userId) {
+			continue
+		}
+
+		overwrites = append(overwrites, channel.PermissionOverwrite{
+			Id:    userId,
+			Type:  channel.PermissionTypeMember,
+			Allow: permission.BuildPermissions(readOnlyAllowed...),
+			Deny:  permission.BuildPermissions(readOnlyDenied...),
+		})
+	}
+
+	for _, roleId := range supportRoles {
+		if Contains(adminRoleTargets, roleId) {
+			continue
+		}
+
+		overwrites = append(overwrites, channel.PermissionOverwrite{
+			Id:    roleId,
+			Type:  channel.PermissionTypeRole,
+			Allow: permission.BuildPermissions(readOnlyAllowed...),
+			Deny:  permission.BuildPermissions(readOnlyDenied...),
+		})
+	}
+
+	return overwrites, nil
+}
+
+// GenerateTicketOverwrites builds the overwrites for an unclaimed ticket, granting access to the opener, the bot and
+// every support representative assigned to the ticket's panel. selfAllow are the permissions given to the bot.
+func GenerateTicketOverwrites(ctx context.Context, botContext *botcontext.BotContext, ticket database.Ticket, selfAllow uint64) ([]channel.PermissionOverwrite, error) {
+	additionalPermissions, err := dbclient.Client.TicketPermissions.Get(ctx, ticket.GuildId)
+	if err != nil {
+		return nil, err
+	}
+
+	integrationRoleId, err := GetIntegrationRoleId(ctx, botContext, ticket.GuildId)
+	if err != nil {
+		return nil, err
+	}
+
+	overwrites := []channel.PermissionOverwrite{
+		everyoneOverwrite(ticket.GuildId),
+		buildUserOverwrite(ticket.UserId, additionalPermissions),
+	}
+
+	if integrationRoleId == nil {
+		overwrites = append(overwrites, channel.PermissionOverwrite{
+			Id:    botContext.BotId,
+			Type:  channel.PermissionTypeMember,
+			Allow: selfAllow,
+		})
+	} else {
+		overwrites = append(overwrites, channel.PermissionOverwrite{
+			Id:    *integrationRoleId,
+			Type:  channel.PermissionTypeRole,
+			Allow: selfAllow,
+		})
+	}
+
+	var panel *database.Panel
+	if ticket.PanelId != nil {
+		tmp, err := dbclient.Client.Panel.GetById(ctx, *ticket.PanelId)
+		if err != nil {
+			return nil, err
+		}
+
+		if tmp.PanelId != 0 {
+			panel = &tmp
+		}
+	}
+
+	var allowedUsers, allowedRoles []uint64
+
+	// Should we add the default team
+	if panel == nil || panel.WithDefaultTeam {
+		supportUsers, err := dbclient.Client.Permissions.GetSupport(ctx, ticket.GuildId)
+		if err != nil {
+			return nil, err
+		}
+
+		supportRoles, err := dbclient.Client.RolePermissions.GetSupportRoles(ctx, ticket.GuildId)
+		if err != nil {
+			return nil, err
+		}
+
+		allowedUsers = append(allowedUsers, supportUsers...)
+		allowedRoles = append(allowedRoles, supportRoles...)
+	}
+
+	if panel != nil {
+		teamUsers, err := dbclient.Client.SupportTeamMembers.GetAllSupportMembersForPanel(ctx, panel.PanelId)
+		if err != nil {
+			return nil, err
+		}
+
+		teamRoles, err := dbclient.Client.SupportTeamRoles.GetAllSupportRolesForPanel(ctx, panel.PanelId)
+		if err != nil {
+			return nil, err
+		}
+
+		allowedUsers = append(allowedUsers, teamUsers...)
+		allowedRoles = append(allowedRoles, teamRoles...)
+	}
+
+	for _, userId := range allowedUsers {
+		if userId == botContext.BotId {
+			continue // Already added overwrite above
+		}
+
+		overwrites = append(overwrites, standardOverwrite(userId, channel.PermissionTypeMember))
+	}
+
+	for _, roleId := range allowedRoles {
+		if integrationRoleId != nil && roleId == *integrationRoleId {
+			continue
+		}
+
+		overwrites = append(overwrites, standardOverwrite(roleId, channel.PermissionTypeRole))
+	}
+
+	return overwrites, nil
+}
@@ -0,0 +1,214 @@
+package utils
+
+import (
+	"context"
+	"errors"
+
+	"github.com/TicketsBot-cloud/dashboard/botcontext"
+	dbclient "github.com/TicketsBot-cloud/dashboard/database"
+	"github.com/TicketsBot-cloud/database"
+	"github.com/rxdn/gdl/objects/channel"
+	"github.com/rxdn/gdl/permission"
+	"github.com/rxdn/gdl/rest"
+)
+
+// ErrThreadRoleParticipant is returned when adding or removing a role to a thread ticket: Discord threads do not have
+// permission overwrites, so only individual members can be added to them
+var ErrThreadRoleParticipant = errors.New("roles cannot be added to thread tickets")
+
+// AddTicketParticipant grants a user or role access to the ticket, in the same way as the /add command. Users are
+// recorded as ticket members; roles only exist as an overwrite on the ticket channel.
+func AddTicketParticipant(ctx context.Context, botContext *botcontext.BotContext, ticket database.Ticket, id uint64, overwriteType channel.PermissionOverwriteType) error {
+	if ticket.ChannelId == nil {
+		return ErrTicketChannelMissing
+	}
+
+	if ticket.IsThread && overwriteType != channel.PermissionTypeMember {
+		return ErrThreadRoleParticipant
+	}
+
+	if ticket.IsThread {
+		if err := rest.AddThreadMember(ctx, botContext.Token, botContext.RateLimiter, *ticket.ChannelId, id); err != nil {
+			return err
+		}
+	} else {
+		additionalPermissions, err := dbclient.Client.TicketPermissions.Get(ctx, ticket.GuildId)
+		if err != nil {
+			return err
+		}
+
+		overwrite := buildParticipantOverwrite(id, overwriteType, additionalPermissions)
+		if err := rest.EditChannelPermissions(ctx, botContext.Token, botContext.RateLimiter, *ticket.ChannelId, overwrite); err != nil {
+			return err
+		}
+	}
+
+	if overwriteType == channel.PermissionTypeMember {
+		return dbclient.Client.TicketMembers.Add(ctx, ticket.GuildId, ticket.Id, id)
+	}
+
+	return nil
+}
+
+// RemoveTicketParticipant revokes a user or role's access to the ticket, in the same way as the /remove command
+func RemoveTicketParticipant(ctx context.Context, botContext *botcontext.BotContext, ticket database.Ticket, id uint64, overwriteType channel.PermissionOverwriteType) error {
+	if ticket.ChannelId == nil {
+		return ErrTicketChannelMissing
+	}
+
+	if ticket.IsThread && overwriteType != channel.PermissionTypeMember {
+		return ErrThreadRoleParticipant
+	}
+
+	if overwriteType == channel.PermissionTypeMember {
+		if err := dbclient.Client.TicketMembers.Delete(ctx, ticket.GuildId, ticket.Id, id); err != nil {
+			return err
+		}
+	}
+
+	if ticket.IsThread {
+		return rest.RemoveThreadMember(ctx, botContext.Token, botContext.RateLimiter, *ticket.ChannelId, id)
+	}
+
+	// Explicitly deny users, as they may still be able to see the ticket through a role. Roles have their overwrite
+	// removed instead, as denying a role would also lock out staff who happen to hold it.
+	if overwriteType == channel.PermissionTypeMember {
+		overwrite := channel.PermissionOverwrite{
+			Id:    id,
+			Type:  channel.PermissionTypeMember,
+			Allow: 0,
+			Deny:  permission.BuildPermissions(standardPermissions[:]...),
+		}
+
+		return rest.EditChannelPermissions(ctx, botContext.Token, botContext.RateLimiter, *ticket.ChannelId, overwrite)
+	}
+
+	return rest.DeleteChannelPermissions(ctx, botContext.Token, botContext.RateLimiter, *ticket.ChannelId, id)
+}
+
+type overwriteKey struct {
+	Id   uint64
+	Type channel.PermissionOverwriteType
+}
+
+// mergeParticipantOverwrites adds the ticket's participants to overwrites generated for a claim or unclaim, which
+// otherwise only cover the opener, staff and the bot, so that rebuilding the channel's permissions does not remove
+// anyone added to the ticket. Participants are the ticket's members, and any other user or role holding the overwrite
+// given by AddTicketParticipant, as added roles are not recorded. Users removed with RemoveTicketParticipant keep their
+// deny overwrite. Existing overwrites for staff are dropped, as their access is decided by the generated overwrites,
+// and the participant overwrite can be identical to the one given to staff.
+func mergeParticipantOverwrites(
+	overwrites, existing []channel.PermissionOverwrite,
+	memberIds []uint64,
+	staff map[overwriteKey]bool,
+	additionalPermissions database.TicketPermissions,
+) []channel.PermissionOverwrite {
+	seen := make(map[overwriteKey]bool)
+	for _, overwrite := range overwrites {
+		seen[overwriteKey{overwrite.Id, overwrite.Type}] = true
+	}
+
+	merged := overwrites
+
+	add := func(overwrite channel.PermissionOverwrite) {
+		key := overwriteKey{overwrite.Id, overwrite.Type}
+		if !seen[key] {
+			seen[key] = true
+			merged = append(merged, overwrite)
+		}
+	}
+
+	removedDeny := permission.BuildPermissions(standardPermissions[:]...)
+	for _, overwrite := range existing {
+		if staff[overwriteKey{overwrite.Id, overwrite.Type}] {
+			continue
+		}
+
+		participant := buildParticipantOverwrite(overwrite.Id, overwrite.Type, additionalPermissions)
+		isParticipant := overwrite.Allow == participant.Allow && overwrite.Deny == participant.Deny
+		isRemoved := overwrite.Type == channel.PermissionTypeMember && overwrite.Allow == 0 && overwrite.Deny == removedDeny
+
+		if isParticipant || isRemoved {
+			add(overwrite)
+		}
+	}
+
+	// Members were added explicitly, so keep access even if they are staff who would otherwise lose it to the claim
+	for _, userId := range memberIds {
+		add(buildUserOverwrite(userId, additionalPermissions))
+	}
+
+	return merged
+}
+
+// withParticipantOverwrites merges the ticket's participants into overwrites generated for its channel
+func withParticipantOverwrites(ctx context.Context, ticket database.Ticket, overwrites, existing []channel.PermissionOverwrite) ([]channel.PermissionOverwrite, error) {
+	memberIds, err := dbclient.Client.TicketMembers.Get(ctx, ticket.GuildId, ticket.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	staff, err := getStaffOverwriteKeys(ctx, ticket)
+	if err != nil {
+		return nil, err
+	}
+
+	additionalPermissions, err := dbclient.Client.TicketPermissions.Get(ctx, ticket.GuildId)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeParticipantOverwrites(overwrites, existing, memberIds, staff, additionalPermissions), nil
+}
+
+// getStaffOverwriteKeys returns every user and role that may have been given access to the ticket as staff
+func getStaffOverwriteKeys(ctx context.Context, ticket database.Ticket) (map[overwriteKey]bool, error) {
+	users, err := dbclient.Client.Permissions.GetSupport(ctx, ticket.GuildId)
+	if err != nil {
+		return nil, err
+	}
+
+	adminUsers, err := dbclient.Client.Permissions.GetAdmins(ctx, ticket.GuildId)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := dbclient.Client.RolePermissions.GetSupportRoles(ctx, ticket.GuildId)
+	if err != nil {
+		return nil, err
+	}
+
+	adminRoles, err := dbclient.Client.RolePermissions.GetAdminRoles(ctx, ticket.GuildId)
+	if err != nil {
+		return nil, err
+	}
+
+	users = append(users, adminUsers...)
+	roles = append(roles, adminRoles...)
+
+	if ticket.PanelId != nil {
+		teamUsers, err := dbclient.Client.SupportTeamMembers.GetAllSupportMembersForPanel(ctx, *ticket.PanelId)
+		if err != nil {
+			return nil, err
+		}
+
+		teamRoles, err := dbclient.Client.SupportTeamRoles.GetAllSupportRolesForPanel(ctx, *ticket.PanelId)
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, teamUsers...)
+		roles = append(roles, teamRoles...)
+	}
+
+	staff := make(map[overwriteKey]bool, len(users)+len(roles))
+	for _, userId := range users {
+		staff[overwriteKey{userId, channel.PermissionTypeMember}] = true
+	}
+
+	for _, roleId := range roles {
+		staff[overwriteKey{roleId, channel.PermissionTypeRole}] = true
+	}
+
+	return staff, nil
+}
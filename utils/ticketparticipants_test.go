@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/TicketsBot-cloud/database"
+	"github.com/rxdn/gdl/objects/channel"
+	"github.com/rxdn/gdl/permission"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testGuildId   uint64 = 1
+	testOpenerId  uint64 = 2
+	testClaimerId uint64 = 3
+	testBotId     uint64 = 4
+	testSupportId uint64 = 5
+	testTeamRole  uint64 = 6
+)
+
+func findOverwrite(overwrites []channel.PermissionOverwrite, id uint64, overwriteType channel.PermissionOverwriteType) (channel.PermissionOverwrite, bool) {
+	for _, overwrite := range overwrites {
+		if overwrite.Id == id && overwrite.Type == overwriteType {
+			return overwrite, true
+		}
+	}
+
+	return channel.PermissionOverwrite{}, false
+}
+
+// The overwrites generated when a ticket is claimed and support cannot view it
+func testClaimedOverwrites(additionalPermissions database.TicketPermissions) []channel.PermissionOverwrite {
+	return []channel.PermissionOverwrite{
+		buildUserOverwrite(testOpenerId, additionalPermissions),
+		everyoneOverwrite(testGuildId),
+		standardOverwrite(testClaimerId, channel.PermissionTypeMember),
+		standardOverwrite(testBotId, channel.PermissionTypeMember),
+	}
+}
+
+func TestClaimKeepsAddedParticipants(t *testing.T) {
+	additionalPermissions := database.TicketPermissions{AttachFiles: true}
+
+	const (
+		addedUser   uint64 = 10
+		addedRole   uint64 = 11
+		removedUser uint64 = 12
+		memberOnly  uint64 = 13
+	)
+
+	// The channel's overwrites before the claim, after participants were added and removed
+	existing := []channel.PermissionOverwrite{
+		buildUserOverwrite(testOpenerId, additionalPermissions),
+		everyoneOverwrite(testGuildId),
+		standardOverwrite(testSupportId, channel.PermissionTypeMember),
+		standardOverwrite(testTeamRole, channel.PermissionTypeRole),
+		buildParticipantOverwrite(addedUser, channel.PermissionTypeMember, additionalPermissions),
+		buildParticipantOverwrite(addedRole, channel.PermissionTypeRole, additionalPermissions),
+		{
+			Id:   removedUser,
+			Type: channel.PermissionTypeMember,
+			Deny: permission.BuildPermissions(standardPermissions[:]...),
+		},
+	}
+
+	staff := map[overwriteKey]bool{
+		{testSupportId, channel.PermissionTypeMember}: true,
+		{testTeamRole, channel.PermissionTypeRole}:    true,
+	}
+
+	merged := mergeParticipantOverwrites(
+		testClaimedOverwrites(additionalPermissions),
+		existing,
+		[]uint64{addedUser, memberOnly},
+		staff,
+		additionalPermissions,
+	)
+
+	for _, participant := range []overwriteKey{
+		{addedUser, channel.PermissionTypeMember},
+		{addedRole, channel.PermissionTypeRole},
+		{memberOnly, channel.PermissionTypeMember},
+	} {
+		overwrite, ok := findOverwrite(merged, participant.Id, participant.Type)
+		if assert.True(t, ok, "participant %d lost access", participant.Id) {
+			assert.Equal(t, buildParticipantOverwrite(participant.Id, participant.Type, additionalPermissions), overwrite)
+		}
+	}
+
+	removed, ok := findOverwrite(merged, removedUser, channel.PermissionTypeMember)
+	assert.True(t, ok)
+	assert.Zero(t, removed.Allow)
+
+	// Support lose access to the claimed ticket
+	_, ok = findOverwrite(merged, testSupportId, channel.PermissionTypeMember)
+	assert.False(t, ok)
+
+	_, ok = findOverwrite(merged, testTeamRole, channel.PermissionTypeRole)
+	assert.False(t, ok)
+
+	assert.Len(t, merged, len(testClaimedOverwrites(additionalPermissions))+4)
+}
+
+// With every additional permission enabled, the participant overwrite is identical to the one given to staff
+func TestClaimDropsStaffMatchingParticipantOverwrite(t *testing.T) {
+	additionalPermissions := database.TicketPermissions{AttachFiles: true, EmbedLinks: true, AddReactions: true}
+
+	staffOverwrite := standardOverwrite(testTeamRole, channel.PermissionTypeRole)
+	assert.Equal(t, buildParticipantOverwrite(testTeamRole, channel.PermissionTypeRole, additionalPermissions), staffOverwrite)
+
+	merged := mergeParticipantOverwrites(
+		testClaimedOverwrites(additionalPermissions),
+		[]channel.PermissionOverwrite{staffOverwrite},
+		nil,
+		map[overwriteKey]bool{{testTeamRole, channel.PermissionTypeRole}: true},
+		additionalPermissions,
+	)
+
+	_, ok := findOverwrite(merged, testTeamRole, channel.PermissionTypeRole)
+	assert.False(t, ok)
+}
+
+func TestUnclaimKeepsGeneratedOverwrite(t *testing.T) {
+	additionalPermissions := database.TicketPermissions{}
+
+	generated := []channel.PermissionOverwrite{
+		standardOverwrite(testSupportId, channel.PermissionTypeMember),
+	}
+
+	// A staff member who was also added to the ticket keeps the broader staff overwrite
+	merged := mergeParticipantOverwrites(generated, nil, []uint64{testSupportId}, nil, additionalPermissions)
+	assert.Equal(t, generated, merged)
+}
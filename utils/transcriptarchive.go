@@ -0,0 +1,228 @@
+package utils
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/TicketsBot-cloud/archiverclient"
+	"github.com/TicketsBot-cloud/dashboard/chatreplica"
+	"github.com/TicketsBot-cloud/dashboard/config"
+	dbclient "github.com/TicketsBot-cloud/dashboard/database"
+	"github.com/TicketsBot-cloud/dashboard/log"
+	"github.com/TicketsBot-cloud/dashboard/s3"
+	"github.com/minio/minio-go/v7"
+	"go.uber.org/zap"
+)
+
+const (
+	transcriptArchivePageSize         = 100
+	transcriptArchiveProgressInterval = 25
+)
+
+// TranscriptArchiveExpiry is how long an archive's zip file is kept once built. Archives contain decrypted transcripts,
+// so are also deleted early if a transcript they may contain is redacted or purged.
+const TranscriptArchiveExpiry = 7 * 24 * time.Hour
+
+// TranscriptArchiveObjectKey returns where an archive's zip file is stored in the archive bucket
+func TranscriptArchiveObjectKey(archive dbclient.TranscriptArchive) string {
+	return fmt.Sprintf("transcripts/%d/%s.zip", archive.GuildId, archive.Id)
+}
+
+// BuildTranscriptArchive packages the transcripts matching the archive's filters into a zip file, containing both the
+// raw JSON and rendered HTML of each, and streams it to the archive bucket. Progress is recorded as it goes, and the
+// archive is marked as failed if it can not be completed.
+func BuildTranscriptArchive(ctx context.Context, archive dbclient.TranscriptArchive) error {
+	objectKey := TranscriptArchiveObjectKey(archive)
+
+	if err := dbclient.Dashboard.TranscriptArchives.SetProgress(ctx, archive.Id, 0, 0); err != nil {
+		return err
+	}
+
+	reader, writer := io.Pipe()
+
+	uploadErr := make(chan error, 1)
+	go func() {
+		_, err := s3.S3Client.PutObject(ctx, config.Conf.S3Import.ArchiveBucket, objectKey, reader, -1, minio.PutObjectOptions{
+			ContentType: "application/zip",
+		})
+
+		// Unblock the writer if the upload failed part way through
+		reader.CloseWithError(err)
+		uploadErr <- err
+	}()
+
+	processed, skipped, err := writeTranscriptArchive(ctx, writer, archive)
+	writer.CloseWithError(err)
+
+	if uploadErr := <-uploadErr; err == nil {
+		err = uploadErr
+	}
+
+	if err != nil {
+		// The error itself may contain internal details, so is only logged by the caller
+		if failErr := dbclient.Dashboard.TranscriptArchives.SetFailed(context.Background(), archive.Id, "An error occurred while building the archive"); failErr != nil {
+			return failErr
+		}
+
+		return err
+	}
+
+	return dbclient.Dashboard.TranscriptArchives.SetComplete(ctx, archive.Id, processed, skipped, objectKey)
+}
+
+// DeleteTranscriptArchive removes an archive's zip file from the archive bucket, and marks the archive as expired
+func DeleteTranscriptArchive(ctx context.Context, archive dbclient.TranscriptArchive) error {
+	if archive.ObjectKey != nil {
+		if err := s3.S3Client.RemoveObject(ctx, config.Conf.S3Import.ArchiveBucket, *archive.ObjectKey, minio.RemoveObjectOptions{}); err != nil {
+			return err
+		}
+	}
+
+	return dbclient.Dashboard.TranscriptArchives.SetExpired(ctx, archive.Id)
+}
+
+// DeleteTranscriptArchivesContaining deletes the archives that may contain a ticket's transcript, so that redacted or
+// purged content does not outlive the change in a downloadable zip file.
+func DeleteTranscriptArchivesContaining(ctx context.Context, guildId uint64, ticketId int) error {
+	if config.Conf.S3Import.ArchiveBucket == "" {
+		return nil
+	}
+
+	archives, err := dbclient.Dashboard.TranscriptArchives.GetContaining(ctx, guildId, ticketId)
+	if err != nil {
+		return err
+	}
+
+	for _, archive := range archives {
+		if err := DeleteTranscriptArchive(ctx, archive); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteExpiredTranscriptArchives deletes up to limit archives older than TranscriptArchiveExpiry, returning how many
+// were deleted.
+func DeleteExpiredTranscriptArchives(ctx context.Context, limit int) (int, error) {
+	if config.Conf.S3Import.ArchiveBucket == "" {
+		return 0, nil
+	}
+
+	archives, err := dbclient.Dashboard.TranscriptArchives.GetExpired(ctx, time.Now().Add(-TranscriptArchiveExpiry), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, archive := range archives {
+		if err := DeleteTranscriptArchive(ctx, archive); err != nil {
+			return i, err
+		}
+	}
+
+	return len(archives), nil
+}
+
+func writeTranscriptArchive(ctx context.Context, w io.Writer, archive dbclient.TranscriptArchive) (processed, skipped int, _ error) {
+	zipWriter := zip.NewWriter(w)
+
+	opts := archive.QueryOptions()
+	opts.Limit = transcriptArchivePageSize
+
+	// Tickets closed after the archive was requested are excluded, so that the archive matches the total shown
+	for processed+skipped < archive.Total {
+		tickets, err := dbclient.Dashboard.Transcripts.Get(ctx, opts)
+		if err != nil {
+			return processed, skipped, err
+		}
+
+		for _, ticket := range tickets {
+			if processed+skipped >= archive.Total {
+				break
+			}
+
+			ok, err := writeArchivedTranscript(ctx, zipWriter, archive.GuildId, ticket.Id, ticket.HasTranscript)
+			if err != nil {
+				return processed, skipped, err
+			}
+
+			if ok {
+				processed++
+			} else {
+				skipped++
+			}
+
+			if (processed+skipped)%transcriptArchiveProgressInterval == 0 {
+				if err := dbclient.Dashboard.TranscriptArchives.SetProgress(ctx, archive.Id, processed, skipped); err != nil {
+					return processed, skipped, err
+				}
+			}
+		}
+
+		if len(tickets) < transcriptArchivePageSize {
+			break
+		}
+
+		cursor := opts.CursorFor(tickets[len(tickets)-1])
+		opts.After = &cursor
+	}
+
+	return processed, skipped, zipWriter.Close()
+}
+
+// writeArchivedTranscript adds a ticket's transcript to the zip file, returning false if it has no transcript
+func writeArchivedTranscript(ctx context.Context, zipWriter *zip.Writer, guildId uint64, ticketId int, hasTranscript bool) (bool, error) {
+	if !hasTranscript {
+		return false, nil
+	}
+
+	transcript, err := ArchiverClient.Get(ctx, guildId, ticketId)
+	if err != nil {
+		if errors.Is(err, archiverclient.ErrNotFound) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	encoded, err := json.Marshal(transcript)
+	if err != nil {
+		return false, err
+	}
+
+	// The native renderer is used, rather than the render service, as its output is self-contained, and so can be
+	// read offline
+	payload := chatreplica.FromTranscript(transcript, ticketId)
+	if err := ResolveTranscriptMentions(ctx, guildId, &payload); err != nil {
+		log.Logger.Warn("Failed to resolve transcript mentions", zap.Error(err), zap.Uint64("guild_id", guildId), zap.Int("ticket_id", ticketId))
+	}
+
+	html, err := chatreplica.RenderNative(payload)
+	if err != nil {
+		return false, err
+	}
+
+	for _, entry := range []struct {
+		name string
+		data []byte
+	}{
+		{fmt.Sprintf("ticket-%d.json", ticketId), encoded},
+		{fmt.Sprintf("ticket-%d.html", ticketId), html},
+	} {
+		file, err := zipWriter.Create(entry.name)
+		if err != nil {
+			return false, err
+		}
+
+		if _, err := file.Write(entry.data); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
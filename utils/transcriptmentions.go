@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"context"
+	"errors"
+
+	"github.com/TicketsBot-cloud/dashboard/botcontext"
+	"github.com/TicketsBot-cloud/dashboard/chatreplica"
+	"github.com/TicketsBot-cloud/dashboard/rpc/cache"
+	cache2 "github.com/rxdn/gdl/cache"
+)
+
+// ResolveTranscriptMentions adds the channels and roles that are mentioned in a transcript, but were not stored with
+// it, to its entities, so that they are rendered by name rather than as raw IDs. Channels and roles that have since
+// been deleted are left unresolved.
+func ResolveTranscriptMentions(ctx context.Context, guildId uint64, payload *chatreplica.Payload) error {
+	channelIds, roleIds := payload.UnresolvedMentions()
+	if len(channelIds) == 0 && len(roleIds) == 0 {
+		return nil
+	}
+
+	botContext, err := botcontext.ContextForGuild(guildId)
+	if err != nil {
+		return err
+	}
+
+	if len(channelIds) > 0 {
+		channels, err := botContext.GetGuildChannels(ctx, guildId)
+		if err != nil {
+			return err
+		}
+
+		names := make(map[uint64]string)
+		for _, ch := range channels {
+			names[ch.Id] = ch.Name
+		}
+
+		for _, channelId := range channelIds {
+			if name, ok := names[channelId]; ok {
+				payload.AddChannel(channelId, name)
+				continue
+			}
+
+			// Threads are not included in the guild's channel list
+			ch, err := cache.Instance.GetChannel(ctx, channelId)
+			if err == nil {
+				if ch.GuildId == guildId {
+					payload.AddChannel(channelId, ch.Name)
+				}
+			} else if !errors.Is(err, cache2.ErrNotFound) {
+				return err
+			}
+		}
+	}
+
+	if len(roleIds) > 0 {
+		roles, err := botContext.GetGuildRoles(ctx, guildId)
+		if err != nil {
+			return err
+		}
+
+		wanted := make(map[uint64]bool)
+		for _, roleId := range roleIds {
+			wanted[roleId] = true
+		}
+
+		for _, role := range roles {
+			if wanted[role.Id] {
+				payload.AddRole(role.Id, role.Name, int(role.Color))
+			}
+		}
+	}
+
+	return nil
+}
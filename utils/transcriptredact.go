@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/TicketsBot-cloud/dashboard/chatreplica"
+	dbclient "github.com/TicketsBot-cloud/dashboard/database"
+	"github.com/TicketsBot-cloud/dashboard/redis"
+)
+
+// RedactTranscript removes a message, or a single attachment if attachmentId is non-nil, from a stored transcript,
+// re-uploads it, and records who made the redaction and whether it was carried out in full. The cached render and any
+// archives containing the transcript are invalidated, and the search index rebuilt if the guild uses search, so that the
+// redacted content can no longer be seen or found.
+func RedactTranscript(ctx context.Context, guildId uint64, ticketId int, messageId uint64, attachmentId *uint64, redactedBy uint64) error {
+	transcript, err := ArchiverClient.Get(ctx, guildId, ticketId)
+	if err != nil {
+		return err
+	}
+
+	if attachmentId == nil {
+		err = chatreplica.RedactMessage(&transcript, messageId)
+	} else {
+		err = chatreplica.RedactAttachment(&transcript, messageId, *attachmentId)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// Transcripts are always stored in the v2 format, which Get converts older transcripts to
+	data, err := json.Marshal(transcript)
+	if err != nil {
+		return err
+	}
+
+	// Record the redaction before changing the transcript, so that there is an audit record of it even if a later
+	// step fails
+	id, err := dbclient.Dashboard.TranscriptRedactions.Create(ctx, dbclient.TranscriptRedaction{
+		GuildId:      guildId,
+		TicketId:     ticketId,
+		MessageId:    messageId,
+		AttachmentId: attachmentId,
+		RedactedBy:   redactedBy,
+		RedactedAt:   time.Now(),
+		Status:       dbclient.TranscriptRedactionStatusPending,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := ArchiverClient.ImportTranscript(ctx, guildId, ticketId, data); err != nil {
+		if failErr := dbclient.Dashboard.TranscriptRedactions.SetStatus(context.Background(), id, dbclient.TranscriptRedactionStatusFailed); failErr != nil {
+			return failErr
+		}
+
+		return err
+	}
+
+	// If a later step fails, the redaction is left as applied, as copies of the content may remain
+	if err := dbclient.Dashboard.TranscriptRedactions.SetStatus(context.Background(), id, dbclient.TranscriptRedactionStatusApplied); err != nil {
+		return err
+	}
+
+	if err := redis.Client.InvalidateTranscriptRender(ctx, guildId, ticketId); err != nil {
+		return err
+	}
+
+	if err := DeleteTranscriptArchivesContaining(ctx, guildId, ticketId); err != nil {
+		return err
+	}
+
+	if err := IndexTranscript(ctx, guildId, ticketId); err != nil {
+		return err
+	}
+
+	return dbclient.Dashboard.TranscriptRedactions.SetStatus(ctx, id, dbclient.TranscriptRedactionStatusComplete)
+}
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/TicketsBot-cloud/archiverclient"
+	dbclient "github.com/TicketsBot-cloud/dashboard/database"
+	"github.com/TicketsBot-cloud/dashboard/log"
+	"github.com/TicketsBot-cloud/dashboard/redis"
+	"go.uber.org/zap"
+)
+
+// PurgeExpiredTranscripts deletes up to limit transcripts that have outlived their retention period, returning how
+// many were purged. A transcript that fails to delete is skipped, and retried after a backoff.
+func PurgeExpiredTranscripts(ctx context.Context, limit int) (int, error) {
+	expired, err := dbclient.Dashboard.TranscriptRetention.GetExpired(ctx, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	var purged int
+	for _, transcript := range expired {
+		if err := purgeTranscript(ctx, transcript); err != nil {
+			if ctx.Err() != nil {
+				return purged, ctx.Err()
+			}
+
+			log.Logger.Warn(
+				"Failed to purge expired transcript",
+				zap.Error(err),
+				zap.Uint64("guild_id", transcript.GuildId),
+				zap.Int("ticket_id", transcript.TicketId),
+			)
+
+			if err := dbclient.Dashboard.TranscriptRetention.RecordFailure(ctx, transcript.GuildId, transcript.TicketId); err != nil {
+				return purged, err
+			}
+
+			continue
+		}
+
+		purged++
+	}
+
+	return purged, nil
+}
+
+func purgeTranscript(ctx context.Context, transcript dbclient.ExpiredTranscript) error {
+	if err := ArchiverRetriever.DeleteTicket(ctx, transcript.GuildId, transcript.TicketId); err != nil {
+		// The archiver does not return a distinguishable error if the transcript is already gone, so check for it, so
+		// that a missing transcript does not block the purge forever
+		if _, getErr := ArchiverClient.Get(ctx, transcript.GuildId, transcript.TicketId); !errors.Is(getErr, archiverclient.ErrNotFound) {
+			return err
+		}
+	}
+
+	if err := dbclient.Client.Tickets.SetHasTranscript(ctx, transcript.GuildId, transcript.TicketId, false); err != nil {
+		return err
+	}
+
+	if err := dbclient.Dashboard.TranscriptSearch.Delete(ctx, transcript.GuildId, transcript.TicketId); err != nil {
+		return err
+	}
+
+	if err := redis.Client.InvalidateTranscriptRender(ctx, transcript.GuildId, transcript.TicketId); err != nil {
+		return err
+	}
+
+	if err := DeleteTranscriptArchivesContaining(ctx, transcript.GuildId, transcript.TicketId); err != nil {
+		return err
+	}
+
+	if err := dbclient.Dashboard.TranscriptRetention.ClearFailure(ctx, transcript.GuildId, transcript.TicketId); err != nil {
+		return err
+	}
+
+	return dbclient.Dashboard.TranscriptPurgeLog.Create(ctx, dbclient.TranscriptPurge{
+		GuildId:       transcript.GuildId,
+		TicketId:      transcript.TicketId,
+		PanelId:       transcript.PanelId,
+		RetentionDays: transcript.RetentionDays,
+		ClosedAt:      transcript.ClosedAt,
+		PurgedAt:      time.Now(),
+	})
+}
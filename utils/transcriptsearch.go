@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"html"
+	"strings"
+
+	"github.com/TicketsBot-cloud/archiverclient"
+	dbclient "github.com/TicketsBot-cloud/dashboard/database"
+	"github.com/TicketsBot-cloud/dashboard/log"
+	"go.uber.org/zap"
+)
+
+// IndexTranscript fetches a ticket's transcript from the archiver, and replaces its messages in the search index. Does
+// nothing if the guild has not enabled search, so that guilds are never indexed without opting in.
+func IndexTranscript(ctx context.Context, guildId uint64, ticketId int) error {
+	enabled, err := dbclient.Dashboard.TranscriptSearch.IsEnabled(ctx, guildId)
+	if err != nil {
+		return err
+	}
+
+	if !enabled {
+		return nil
+	}
+
+	if err := indexTranscript(ctx, guildId, ticketId); err != nil && !errors.Is(err, dbclient.ErrTranscriptSearchDisabled) {
+		return err
+	}
+
+	return nil
+}
+
+func indexTranscript(ctx context.Context, guildId uint64, ticketId int) error {
+	transcript, err := ArchiverClient.Get(ctx, guildId, ticketId)
+	if err != nil {
+		return err
+	}
+
+	messages := make([]dbclient.TranscriptSearchMessage, len(transcript.Messages))
+	for i, message := range transcript.Messages {
+		messages[i] = dbclient.TranscriptSearchMessage{
+			Id:        message.Id,
+			AuthorId:  message.AuthorId,
+			Content:   message.Content,
+			Timestamp: message.Timestamp,
+		}
+	}
+
+	return dbclient.Dashboard.TranscriptSearch.Index(ctx, guildId, ticketId, messages)
+}
+
+// BackfillTranscripts indexes up to limit of the guild's closed tickets that have not been indexed yet. A transcript
+// missing from the archive is indexed with no messages, and any other failure is recorded, so that one bad transcript
+// does not block the tickets behind it. more is true if there may be further tickets to index. Returns
+// dbclient.ErrTranscriptSearchDisabled if the guild has not enabled search.
+func BackfillTranscripts(ctx context.Context, guildId uint64, limit int) (indexed, failed int, more bool, err error) {
+	enabled, err := dbclient.Dashboard.TranscriptSearch.IsEnabled(ctx, guildId)
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	if !enabled {
+		return 0, 0, false, dbclient.ErrTranscriptSearchDisabled
+	}
+
+	ticketIds, err := dbclient.Dashboard.TranscriptSearch.GetUnindexed(ctx, guildId, limit)
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	more = len(ticketIds) == limit
+
+	for _, ticketId := range ticketIds {
+		err := indexTranscript(ctx, guildId, ticketId)
+		if err == nil {
+			indexed++
+			continue
+		}
+
+		if errors.Is(err, dbclient.ErrTranscriptSearchDisabled) {
+			return indexed, failed, false, err
+		} else if errors.Is(err, archiverclient.ErrNotFound) {
+			err = dbclient.Dashboard.TranscriptSearch.Index(ctx, guildId, ticketId, nil)
+		} else {
+			if ctx.Err() != nil {
+				return indexed, failed, more, ctx.Err()
+			}
+
+			log.Logger.Warn("Failed to index transcript", zap.Error(err), zap.Uint64("guild_id", guildId), zap.Int("ticket_id", ticketId))
+			err = dbclient.Dashboard.TranscriptSearch.MarkFailed(ctx, guildId, ticketId)
+			failed++
+		}
+
+		if err != nil {
+			return indexed, failed, more, err
+		}
+	}
+
+	return indexed, failed, more, nil
+}
+
+// FormatSearchSnippet escapes a search snippet for use as HTML, wrapping the highlighted terms in <mark> tags.
+func FormatSearchSnippet(snippet string) string {
+	return strings.NewReplacer(
+		dbclient.HighlightStart, "<mark>",
+		dbclient.HighlightEnd, "</mark>",
+	).Replace(html.EscapeString(snippet))
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/TicketsBot-cloud/dashboard/config"
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+)
+
+// Share tokens are signed with a key derived from the server secret, rather than the secret itself, so that they can
+// never be accepted as session tokens.
+func transcriptShareKey() []byte {
+	key := sha256.Sum256([]byte("transcript-share:" + config.Conf.Server.Secret))
+	return key[:]
+}
+
+func GenerateTranscriptShareToken(linkId uuid.UUID, expiresAt time.Time) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"share_id": linkId.String(),
+		"exp":      expiresAt.Unix(),
+	})
+
+	return token.SignedString(transcriptShareKey())
+}
+
+// ParseTranscriptShareToken verifies the token's signature and expiry, returning the ID of the share link. The caller
+// must still check that the link has not been revoked.
+func ParseTranscriptShareToken(tokenStr string) (uuid.UUID, error) {
+	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+
+		return transcriptShareKey(), nil
+	})
+
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return uuid.Nil, errors.New("token is invalid")
+	}
+
+	// Tokens without an expiry would pass validation
+	if _, ok := claims["exp"]; !ok {
+		return uuid.Nil, errors.New("token is invalid")
+	}
+
+	shareId, ok := claims["share_id"].(string)
+	if !ok {
+		return uuid.Nil, errors.New("token is invalid")
+	}
+
+	return uuid.Parse(shareId)
+}
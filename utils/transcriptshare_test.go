@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TicketsBot-cloud/dashboard/config"
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranscriptShareTokenRoundTrip(t *testing.T) {
+	config.Conf.Server.Secret = "secret"
+
+	linkId := uuid.New()
+	token, err := GenerateTranscriptShareToken(linkId, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	parsed, err := ParseTranscriptShareToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, linkId, parsed)
+}
+
+func TestTranscriptShareTokenExpired(t *testing.T) {
+	config.Conf.Server.Secret = "secret"
+
+	token, err := GenerateTranscriptShareToken(uuid.New(), time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+
+	_, err = ParseTranscriptShareToken(token)
+	assert.Error(t, err)
+}
+
+func TestTranscriptShareTokenRejectsSessionSecret(t *testing.T) {
+	config.Conf.Server.Secret = "secret"
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"share_id": uuid.New().String(),
+		"exp":      time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(config.Conf.Server.Secret))
+	require.NoError(t, err)
+
+	_, err = ParseTranscriptShareToken(token)
+	assert.Error(t, err)
+}